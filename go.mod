@@ -4,6 +4,7 @@ go 1.20
 
 require (
 	github.com/montanaflynn/stats v0.7.1
+	golang.org/x/text v0.14.0
 	gopkg.in/neurosnap/sentences.v1 v1.0.7
 )
 
@@ -21,8 +21,14 @@
 package segment
 
 import (
+	"bufio"
+	"io"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
 
 	"gopkg.in/neurosnap/sentences.v1"
 	"gopkg.in/neurosnap/sentences.v1/data"
@@ -30,23 +36,74 @@ import (
 
 // A Sentence represents a segmented portion of text.
 type Sentence struct {
-	Text string // The sentence's text.
+	Text     string // The sentence's text.
+	Terminal rune   // The sentence's final '.', '?', '!', or '…' (0 if none).
+	Start    int    // The byte offset of the sentence's first character.
+	End      int    // The byte offset just past the sentence's last character.
 }
 
 // punktSentenceTokenizer is an extension of the Go implementation of the Punkt
 // sentence tokenizer (https://github.com/neurosnap/sentences), with a few
 // minor improvements (see https://github.com/neurosnap/sentences/pull/18).
 type punktSentenceTokenizer struct {
-	tokenizer *sentences.DefaultSentenceTokenizer
+	tokenizer        *sentences.DefaultSentenceTokenizer
+	lowerAfterAbbrev bool
+	initials         bool
+	workers          int
+}
+
+type SegmenterOptFunc func(*punktSentenceTokenizer)
+
+// WithLowercaseAfterAbbrev prevents a sentence break after an abbreviation
+// that's followed by a lowercase word (e.g., "approx. five").
+//
+// In addition to the abbreviations known to the model, this applies to a
+// small set of common abbreviations that the model misses (such as "approx",
+// "etc", and "cf"), which are otherwise treated as sentence-final words.
+func WithLowercaseAfterAbbrev(x bool) SegmenterOptFunc {
+	return func(tokenizer *punktSentenceTokenizer) {
+		tokenizer.lowerAfterAbbrev = x
+	}
+}
+
+// WithInitialsHandling prevents sentence breaks within a run of two or more
+// initials (e.g., "E. B. White" or "J. R. R. Tolkien"), where an initial is a
+// single capital letter followed by a period.
+//
+// A lone initial is left to the model, so "I like the letter B. It's nice."
+// is still split.
+func WithInitialsHandling(x bool) SegmenterOptFunc {
+	return func(tokenizer *punktSentenceTokenizer) {
+		tokenizer.initials = x
+	}
+}
+
+// WithWorkers segments inputs using n concurrent workers.
+//
+// The input is split into roughly equal chunks at blank lines, which are
+// segmented independently. The result is identical to that of a single
+// worker as long as no sentence spans a blank line -- e.g., a heading without
+// terminal punctuation would otherwise be joined to the following sentence.
+//
+// Any gain depends on the number of available CPUs: on a single CPU, it's no
+// faster than one worker (see BenchmarkPunktWorkers).
+func WithWorkers(n int) SegmenterOptFunc {
+	return func(tokenizer *punktSentenceTokenizer) {
+		tokenizer.workers = n
+	}
 }
 
 // NewPunktSentenceTokenizer creates a new PunktSentenceTokenizer and loads
 // its English model.
-func NewPunktSentenceTokenizer() *punktSentenceTokenizer {
+func NewPunktSentenceTokenizer(opts ...SegmenterOptFunc) *punktSentenceTokenizer {
 	var pt punktSentenceTokenizer
 	var err error
 
-	pt.tokenizer, err = newSentenceTokenizer(nil)
+	for _, applyOpt := range opts {
+		applyOpt(&pt)
+	}
+
+	pt.tokenizer, err = newSentenceTokenizer(nil, &pt)
 	if err != nil {
 		panic(err)
 	}
@@ -56,6 +113,10 @@ func NewPunktSentenceTokenizer() *punktSentenceTokenizer {
 
 // Segment splits text into sentences.
 func (p punktSentenceTokenizer) Segment(text string) []string {
+	if p.workers > 1 {
+		return p.segmentChunks(chunk(text, p.workers))
+	}
+
 	sents := []string{}
 	for _, s := range p.tokenizer.Tokenize(text) {
 		sents = append(sents, strings.TrimSpace(s.Text))
@@ -63,16 +124,250 @@ func (p punktSentenceTokenizer) Segment(text string) []string {
 	return sents
 }
 
+// segmentChunks segments each chunk concurrently, returning their sentences
+// in order.
+func (p punktSentenceTokenizer) segmentChunks(chunks []string) []string {
+	results := make([][]string, len(chunks))
+
+	var wg sync.WaitGroup
+	for i := range chunks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = []string{}
+			for _, s := range p.tokenizer.Tokenize(chunks[i]) {
+				results[i] = append(results[i], strings.TrimSpace(s.Text))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	sents := []string{}
+	for i, r := range results {
+		for _, s := range r {
+			// A chunk's trailing whitespace may yield an empty sentence,
+			// but only the last chunk's is also trailing in text.
+			if s != "" || i == len(results)-1 {
+				sents = append(sents, s)
+			}
+		}
+	}
+	return sents
+}
+
+// A Region is a byte range of text, such as a fenced code block or a table,
+// that SegmentRegions shouldn't split into sentences.
+type Region struct {
+	Start int  // The byte offset of the region's first character.
+	End   int  // The byte offset just past the region's last character.
+	Skip  bool // Whether to omit the region instead of keeping it whole.
+}
+
+// SegmentRegions splits text into sentences, like Segment, except that each
+// of the given regions becomes a single sentence (or none, if it's marked
+// Skip).
+//
+// Regions are handled in order of their Start offsets, are clamped to the
+// bounds of text, and may not overlap: any part of a region that precedes the
+// end of an earlier one is ignored. The text between regions is segmented
+// independently, so a sentence never spans a region boundary.
+func (p punktSentenceTokenizer) SegmentRegions(text string, regions []Region) []string {
+	sorted := make([]Region, len(regions))
+	copy(sorted, regions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	sents, last := []string{}, 0
+	for _, r := range sorted {
+		start, end := r.Start, r.End
+		if start < last {
+			start = last
+		}
+		if end > len(text) {
+			end = len(text)
+		}
+		if start >= end {
+			continue
+		}
+		sents = p.appendGap(sents, text[last:start])
+		if opaque := strings.TrimSpace(text[start:end]); !r.Skip && opaque != "" {
+			sents = append(sents, opaque)
+		}
+		last = end
+	}
+	return p.appendGap(sents, text[last:])
+}
+
+// appendGap appends the non-empty sentences of gap, the text between two
+// regions, to sents.
+func (p punktSentenceTokenizer) appendGap(sents []string, gap string) []string {
+	for _, s := range p.Segment(gap) {
+		if s != "" {
+			sents = append(sents, s)
+		}
+	}
+	return sents
+}
+
+var reBlankLine = regexp.MustCompile(`\n[ \t\r]*\n`)
+
+// chunk splits text into at most n pieces of roughly equal size, only ever
+// splitting before a blank line (which then leads the next piece). No piece
+// is blank (unless text is), so trailing whitespace always stays with the
+// last sentence.
+func chunk(text string, n int) []string {
+	chunks := []string{}
+
+	size, start := len(text)/n, 0
+	end := len(strings.TrimRightFunc(text, unicode.IsSpace))
+	for _, loc := range reBlankLine.FindAllStringIndex(text, -1) {
+		if loc[0] >= end {
+			break
+		} else if loc[0]-start >= size && len(chunks) < n-1 && !isBlank(text[start:loc[0]]) {
+			chunks = append(chunks, text[start:loc[0]])
+			start = loc[0]
+		}
+	}
+
+	return append(chunks, text[start:])
+}
+
+// isBlank determines if s consists entirely of whitespace.
+func isBlank(s string) bool {
+	return strings.TrimSpace(s) == ""
+}
+
+// Sentences splits text into sentences, recording each sentence's terminal
+// punctuation and its byte offsets in text (so that text[s.Start:s.End] ==
+// s.Text).
+func (p punktSentenceTokenizer) Sentences(text string) []Sentence {
+	sents := []Sentence{}
+
+	cursor := 0
+	for _, s := range p.Segment(text) {
+		start := cursor + strings.Index(text[cursor:], s)
+		cursor = start + len(s)
+		sents = append(sents, Sentence{
+			Text: s, Terminal: Terminal(s), Start: start, End: cursor})
+	}
+	return sents
+}
+
+// SegmentReader incrementally segments the text read from r, calling fn with
+// each Sentence (whose offsets are relative to the start of r) in order. It
+// stops at the first error returned by fn or encountered while reading; EOF
+// isn't an error.
+//
+// Text is segmented one paragraph (i.e., blank-line-delimited chunk) at a
+// time, so memory use is bounded by the longest paragraph rather than the
+// length of the input. As with WithWorkers, the result matches that of
+// Sentences as long as no sentence spans a blank line.
+func (p punktSentenceTokenizer) SegmentReader(r io.Reader, fn func(Sentence) error) error {
+	reader := bufio.NewReader(r)
+
+	var chunk strings.Builder
+	base, offset := 0, 0
+
+	flush := func() error {
+		for _, s := range p.Sentences(strings.TrimRightFunc(chunk.String(), unicode.IsSpace)) {
+			s.Start += base
+			s.End += base
+			if err := fn(s); err != nil {
+				return err
+			}
+		}
+		chunk.Reset()
+		return nil
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if strings.TrimSpace(line) == "" {
+			if chunk.Len() > 0 {
+				if ferr := flush(); ferr != nil {
+					return ferr
+				}
+			}
+			base = offset + len(line)
+		} else {
+			chunk.WriteString(line)
+		}
+		offset += len(line)
+
+		if err == io.EOF {
+			if chunk.Len() > 0 {
+				return flush()
+			}
+			return nil
+		}
+	}
+}
+
+// Terminal returns the sentence-final punctuation of s ('.', '?', '!', or
+// '…'), ignoring any closing quotes or brackets (e.g., `He said "Hi!"` ->
+// '!'). It returns 0 if s doesn't end with any.
+func Terminal(s string) rune {
+	s = strings.TrimRight(s, `"')]}’”»`)
+	r, _ := utf8.DecodeLastRuneInString(s)
+	if strings.ContainsRune(".?!…", r) {
+		return r
+	}
+	return 0
+}
+
+// Abbreviations returns the sorted, lowercased abbreviations (without their
+// trailing period) that the tokenizer knows about, including those added by
+// WithLowercaseAfterAbbrev.
+func (p punktSentenceTokenizer) Abbreviations() []string {
+	known := []string{}
+	for abbr := range p.tokenizer.AbbrevTypes {
+		if p.tokenizer.AbbrevTypes.Has(abbr) {
+			known = append(known, abbr)
+		}
+	}
+	if p.lowerAfterAbbrev {
+		for abbr := range lowerAbbrevs {
+			if !p.tokenizer.AbbrevTypes.Has(abbr) {
+				known = append(known, abbr)
+			}
+		}
+	}
+	sort.Strings(known)
+	return known
+}
+
+// DefaultAbbreviations returns the abbreviations known to a default
+// PunktSentenceTokenizer (see Abbreviations).
+func DefaultAbbreviations() []string {
+	return NewPunktSentenceTokenizer().Abbreviations()
+}
+
 type wordTokenizer struct {
 	sentences.DefaultWordTokenizer
 }
 
+// abbrevs are added to those learned by the English Punkt model.
+var abbrevs = []string{"sgt", "gov", "no", "mt"}
+
+// lowerAbbrevs are common abbreviations, missing from the English Punkt
+// model, that are considered by WithLowercaseAfterAbbrev.
+var lowerAbbrevs = map[string]bool{
+	"approx": true, "ca": true, "cf": true, "ch": true, "dept": true,
+	"esp": true, "est": true, "etc": true, "fig": true, "pp": true,
+	"vol": true,
+}
+
 var reAbbr = regexp.MustCompile(`((?:[\w]\.)+[\w]*\.)`)
 var reLooksLikeEllipsis = regexp.MustCompile(`(?:\.\s?){2,}\.`)
 var reEntities = regexp.MustCompile(`Yahoo!`)
 
 // English customized sentence tokenizer.
-func newSentenceTokenizer(s *sentences.Storage) (*sentences.DefaultSentenceTokenizer, error) {
+func newSentenceTokenizer(s *sentences.Storage, pt *punktSentenceTokenizer) (*sentences.DefaultSentenceTokenizer, error) {
 	training := s
 
 	if training == nil {
@@ -88,7 +383,6 @@ func newSentenceTokenizer(s *sentences.Storage) (*sentences.DefaultSentenceToken
 	}
 
 	// supervisor abbreviations
-	abbrevs := []string{"sgt", "gov", "no", "mt"}
 	for _, abbr := range abbrevs {
 		training.AbbrevTypes.Add(abbr)
 	}
@@ -105,10 +399,12 @@ func newSentenceTokenizer(s *sentences.Storage) (*sentences.DefaultSentenceToken
 	}
 
 	multiPunct := &multiPunctWordAnnotation{
-		Storage:      training,
-		TokenParser:  word,
-		TokenGrouper: &sentences.DefaultTokenGrouper{},
-		Ortho:        ortho,
+		Storage:          training,
+		TokenParser:      word,
+		TokenGrouper:     &sentences.DefaultTokenGrouper{},
+		Ortho:            ortho,
+		lowerAfterAbbrev: pt.lowerAfterAbbrev,
+		initials:         pt.initials,
 	}
 
 	annotations = append(annotations, multiPunct)
@@ -165,10 +461,26 @@ type multiPunctWordAnnotation struct {
 	sentences.TokenParser
 	sentences.TokenGrouper
 	sentences.Ortho
+
+	lowerAfterAbbrev bool
+	initials         bool
 }
 
 func (a *multiPunctWordAnnotation) Annotate(tokens []*sentences.Token) []*sentences.Token {
+	// inRun records whether the current pair's first token follows an initial.
+	inRun := false
 	for _, tokPair := range a.TokenGrouper.Group(tokens) {
+		if a.initials && isInitial(tokPair[0].Tok) {
+			next := len(tokPair) == 2 && tokPair[1] != nil && isInitial(tokPair[1].Tok)
+			if inRun || next {
+				tokPair[0].Abbr = true
+				tokPair[0].SentBreak = false
+				inRun = next
+				continue
+			}
+		}
+		inRun = false
+
 		if len(tokPair) < 2 || tokPair[1] == nil {
 			tok := tokPair[0].Tok
 			if strings.Contains(tok, "\n") && strings.Contains(tok, " ") {
@@ -184,6 +496,13 @@ func (a *multiPunctWordAnnotation) Annotate(tokens []*sentences.Token) []*senten
 	return tokens
 }
 
+// isInitial determines if tok is a single capital letter followed by a period
+// (e.g., "J.").
+func isInitial(tok string) bool {
+	r, size := utf8.DecodeRuneInString(tok)
+	return unicode.IsUpper(r) && tok[size:] == "."
+}
+
 // looksInternal determines if tok's punctuation could appear
 // sentence-internally (i.e., parentheses or quotations).
 func looksInternal(tok string) bool {
@@ -200,6 +519,15 @@ func (a *multiPunctWordAnnotation) tokenAnnotation(tokOne, tokTwo *sentences.Tok
 	// This is an expensive calculation, so we only want to do it once.
 	var nextTyp string
 
+	if a.lowerAfterAbbrev && strings.HasSuffix(tokOne.Tok, ".") && a.TokenParser.FirstLower(tokTwo) {
+		typ := a.TokenParser.TypeNoPeriod(tokOne)
+		if a.AbbrevTypes.Has(typ) || lowerAbbrevs[typ] {
+			tokOne.Abbr = true
+			tokOne.SentBreak = false
+			return
+		}
+	}
+
 	// If both tokOne and tokTwo and periods, we're probably in an ellipsis
 	// that wasn't properly tokenized by `WordTokenizer`.
 	if strings.HasSuffix(tokOne.Tok, ".") && tokTwo.Tok == "." {
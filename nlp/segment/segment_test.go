@@ -2,7 +2,11 @@ package segment_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/jdkato/twine/internal"
@@ -37,6 +41,64 @@ func BenchmarkPunkt(b *testing.B) {
 	}
 }
 
+func BenchmarkPunktWorkers(b *testing.B) {
+	text := string(internal.ReadDataFile(filepath.Join("..", "..", "testdata", "sherlock.txt")))
+	for _, n := range []int{1, 4} {
+		segmenter := segment.NewPunktSentenceTokenizer(segment.WithWorkers(n))
+		b.Run(fmt.Sprintf("workers=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = segmenter.Segment(text)
+			}
+		})
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	paragraphs := []string{
+		"Hello World. My name is Jonas.",
+		"What is your name? My name is Jonas.",
+		"There it is! I found it.",
+		"My name is Jonas E. Smith.",
+		"Please turn to p. 55.",
+		"Were Jane and co. at the party?",
+		"They closed the deal with Pitt, Briggs & Co. at noon.",
+	}
+	text := strings.Join(paragraphs, "\n\n")
+
+	expected := segmenter.Segment(text)
+	for _, n := range []int{2, 3, 7, 20} {
+		parallel := segment.NewPunktSentenceTokenizer(segment.WithWorkers(n))
+		if actual := parallel.Segment(text); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("workers=%d: got %q; expected %q", n, actual, expected)
+		}
+	}
+
+	parallel := segment.NewPunktSentenceTokenizer(segment.WithWorkers(4))
+	text = string(internal.ReadDataFile(filepath.Join("..", "..", "testdata", "sherlock.txt")))
+	if !reflect.DeepEqual(parallel.Segment(text), segmenter.Segment(text)) {
+		t.Error("sherlock.txt: expected identical sentences")
+	}
+
+	for _, text := range []string{
+		"A b c.\n\n  \n\nD e f.\n\n",
+		"   \n\n  \n\n",
+		"\n\n  \n\nIt rained all day.\n\n\n\nWhere did you go?  \n\n  \n\n",
+		"The cat sat on the mat.\r\n\r\nWe won!\n \t\nMr. Smith left early.\n\n\n",
+	} {
+		expected := segmenter.Segment(text)
+		for _, n := range []int{2, 3, 7} {
+			parallel := segment.NewPunktSentenceTokenizer(segment.WithWorkers(n))
+			if actual := parallel.Segment(text); !reflect.DeepEqual(actual, expected) {
+				t.Errorf("workers=%d: %q: got %q; expected %q", n, text, actual, expected)
+			}
+		}
+	}
+
+	if actual := parallel.Segment("No blank lines. Just one chunk."); len(actual) != 2 {
+		t.Errorf("got %q; expected 2 sentences", actual)
+	}
+}
+
 func TestEnglishSmartQuotes(t *testing.T) {
 	actualText := "Here is a quote, ”a smart one.” Will this break properly?"
 	actual := segmenter.Segment(actualText)
@@ -136,6 +198,96 @@ func TestEnglishSemicolon(t *testing.T) {
 	}
 }
 
+func TestSentenceTerminal(t *testing.T) {
+	actual := segmenter.Sentences(`Is it over? It is! He said "No." So it ends...  Or not`)
+	expected := []rune{'?', '!', '.', '.', 0}
+
+	if len(actual) != len(expected) {
+		t.Fatalf("Actual: %d, Expected: %d", len(actual), len(expected))
+	}
+
+	for index, sent := range actual {
+		if sent.Terminal != expected[index] {
+			t.Errorf("%s: Actual: %q, Expected: %q", sent.Text, sent.Terminal, expected[index])
+		}
+	}
+}
+
+func TestSentenceOffsets(t *testing.T) {
+	text := "  Dr. Müller arrived.  He left—quickly!\n\nThe end."
+	expected := []string{"Dr. Müller arrived.", "He left—quickly!", "The end."}
+
+	actual := segmenter.Sentences(text)
+	if len(actual) != len(expected) {
+		t.Fatalf("Actual: %d, Expected: %d", len(actual), len(expected))
+	}
+
+	for index, sent := range actual {
+		if sent.Text != expected[index] || text[sent.Start:sent.End] != sent.Text {
+			t.Errorf("%d: Actual: %q at [%d:%d], Expected: %q",
+				index, sent.Text, sent.Start, sent.End, expected[index])
+		}
+	}
+}
+
+func TestDefaultAbbreviations(t *testing.T) {
+	abbrevs := segment.DefaultAbbreviations()
+	if !sort.StringsAreSorted(abbrevs) {
+		t.Error("expected sorted abbreviations")
+	}
+
+	for _, abbr := range []string{"sgt", "gov", "mr", "dr"} {
+		if !internal.StringInSlice(abbr, abbrevs) {
+			t.Errorf("expected %q to be a known abbreviation", abbr)
+		}
+	}
+
+	if !reflect.DeepEqual(abbrevs, segmenter.Abbreviations()) {
+		t.Error("expected the default segmenter to use the default abbreviations")
+	}
+
+	lower := segment.NewPunktSentenceTokenizer(segment.WithLowercaseAfterAbbrev(true)).Abbreviations()
+	if !sort.StringsAreSorted(lower) {
+		t.Error("expected sorted abbreviations")
+	}
+	for _, abbr := range []string{"approx", "etc", "cf", "mr"} {
+		if !internal.StringInSlice(abbr, lower) {
+			t.Errorf("WithLowercaseAfterAbbrev: expected %q to be a known abbreviation", abbr)
+		}
+		if abbr != "mr" && internal.StringInSlice(abbr, abbrevs) {
+			t.Errorf("expected %q to be unknown by default", abbr)
+		}
+	}
+}
+
+func TestLowercaseAfterAbbrev(t *testing.T) {
+	cases := map[string][]string{
+		"The box weighs approx. five pounds.": {
+			"The box weighs approx.", "five pounds."},
+		"We saw cats, dogs, etc. and other pets. Then we left.": {
+			"We saw cats, dogs, etc.", "and other pets.", "Then we left."},
+		"Compare the results, cf. the appendix.": {
+			"Compare the results, cf.", "the appendix."},
+	}
+
+	fixed := segment.NewPunktSentenceTokenizer(segment.WithLowercaseAfterAbbrev(true))
+	for text, before := range cases {
+		if actual := segmenter.Segment(text); !reflect.DeepEqual(actual, before) {
+			t.Errorf("before: got %q; expected %q", actual, before)
+		}
+
+		after := fixed.Segment(text)
+		if len(after) != len(before)-1 || !strings.HasPrefix(text, after[0]) {
+			t.Errorf("after: unexpected sentences %q", after)
+		}
+	}
+
+	expected := []string{"I counted to five etc.", "Then I stopped."}
+	if actual := fixed.Segment("I counted to five etc. Then I stopped."); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %q; expected %q", actual, expected)
+	}
+}
+
 func compareSentences(t *testing.T, actualText string, expected []string, test string) bool {
 	actual := segmenter.Segment(actualText)
 
@@ -488,3 +640,95 @@ func TestGoldenRules(t *testing.T) {
 	}
 	compareSentences(t, actualText, expected, test)*/
 }
+
+func TestSegmentRegions(t *testing.T) {
+	text := "Run the script.\n\n```\nx = 1. y = 2.\n```\n\nIt prints nothing. | a. | b. |\nDone."
+	code := strings.Index(text, "```")
+	table := strings.Index(text, "|")
+	regions := []segment.Region{
+		{Start: table, End: strings.LastIndex(text, "|") + 1, Skip: true},
+		{Start: code, End: strings.LastIndex(text, "```") + 3},
+	}
+
+	tokenizer := segment.NewPunktSentenceTokenizer()
+	observed := tokenizer.SegmentRegions(text, regions)
+	expected := []string{
+		"Run the script.", "```\nx = 1. y = 2.\n```", "It prints nothing.", "Done."}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("SegmentRegions: got %q; expected %q", observed, expected)
+	}
+
+	observed = tokenizer.SegmentRegions("One. Two.", nil)
+	expected = tokenizer.Segment("One. Two.")
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("SegmentRegions(nil): got %q; expected %q", observed, expected)
+	}
+
+	observed = tokenizer.SegmentRegions("One. Two. Three.", []segment.Region{
+		{Start: 5, End: 100}, {Start: 8, End: 12}})
+	expected = []string{"One.", "Two. Three."}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("SegmentRegions(overlap): got %q; expected %q", observed, expected)
+	}
+}
+
+func TestInitialsHandling(t *testing.T) {
+	tokenizer := segment.NewPunktSentenceTokenizer(segment.WithInitialsHandling(true))
+	for text, expected := range map[string][]string{
+		"See E. B. White and A. A. Milne today.": {
+			"See E. B. White and A. A. Milne today."},
+		"J. R. R. Tolkien wrote it. He was British.": {
+			"J. R. R. Tolkien wrote it.", "He was British."},
+		"Ask W. E. B. Du Bois.\n\nThen go.": {"Ask W. E. B. Du Bois.", "Then go."},
+		"I met John F. Kennedy. He smiled.": {"I met John F. Kennedy.", "He smiled."},
+		"I like the letter B. It is nice.":  {"I like the letter B.", "It is nice."},
+		"I met Dr. Smith. The U.S. is big.": {"I met Dr. Smith.", "The U.S. is big."},
+	} {
+		if observed := tokenizer.Segment(text); !reflect.DeepEqual(observed, expected) {
+			t.Errorf("Segment(%q): got %q; expected %q", text, observed, expected)
+		}
+	}
+
+	text := "See E. B. White today."
+	expected := []string{"See E.", "B.", "White today."}
+	if observed := segment.NewPunktSentenceTokenizer().Segment(text); !reflect.DeepEqual(observed, expected) {
+		t.Errorf("Segment(default): got %q; expected %q", observed, expected)
+	}
+}
+
+func TestSegmentReader(t *testing.T) {
+	text := "It began. It went on!\r\n\r\nA new paragraph.\n  \nThe end? Maybe\nnot."
+	expected := segmenter.Sentences(text)
+
+	actual := []segment.Sentence{}
+	err := segmenter.SegmentReader(strings.NewReader(text), func(s segment.Sentence) error {
+		actual = append(actual, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("SegmentReader: got %+v; expected %+v", actual, expected)
+	}
+
+	sherlock := string(internal.ReadDataFile(filepath.Join("..", "..", "testdata", "sherlock.txt")))
+	err = segmenter.SegmentReader(strings.NewReader(sherlock), func(s segment.Sentence) error {
+		if sherlock[s.Start:s.End] != s.Text {
+			return fmt.Errorf("%q has offsets [%d:%d]", s.Text, s.Start, s.End)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	stop := fmt.Errorf("stop")
+	count := 0
+	err = segmenter.SegmentReader(strings.NewReader(text), func(s segment.Sentence) error {
+		count++
+		return stop
+	})
+	if err != stop || count != 1 {
+		t.Errorf("SegmentReader: got %v after %d sentences; expected stop after 1", err, count)
+	}
+}
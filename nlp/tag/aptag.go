@@ -21,15 +21,22 @@
 package tag
 
 import (
+	"encoding/gob"
+	"io"
+	"math"
+	"math/rand"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/jdkato/twine/internal"
 )
 
 var none = regexp.MustCompile(`^(?:0|\*[\w?]\*|\*\-\d{1,3}|\*[A-Z]+\*\-\d{1,3}|\*)$`)
 var keep = regexp.MustCompile(`^\-[A-Z]{3}\-$`)
+var number = regexp.MustCompile(`^[+-]?\d+(?:,\d{3})*(?:\.\d+)?$`)
 
 // AveragedPerceptron is a Averaged Perceptron classifier.
 type AveragedPerceptron struct {
@@ -52,14 +59,94 @@ func NewAveragedPerceptron(weights map[string]map[string]float64,
 // PerceptronTagger is a port of Textblob's "fast and accurate" POS tagger.
 // See https://github.com/sloria/textblob-aptagger for details.
 type PerceptronTagger struct {
-	tagMap map[string]string
-	model  *AveragedPerceptron
+	tagMap    map[string]string
+	model     *AveragedPerceptron
+	smartCase bool
+	caseFold  bool
+	beamWidth int
+	unknown   func(word string) []string
+	trivial   bool
+	suffixes  *SuffixTagger
+	lemmas    bool
+}
+
+type TaggerOptFunc func(*PerceptronTagger)
+
+// WithSmartCaseNormalization populates each Token's Normal field, lowercasing
+// the sentence-initial word unless it was tagged as a proper noun.
+func WithSmartCaseNormalization(x bool) TaggerOptFunc {
+	return func(tagger *PerceptronTagger) {
+		tagger.smartCase = x
+	}
+}
+
+// WithCaseFoldForTagging makes the tagger compute its features from a
+// lowercased copy of sentences written entirely in uppercase (e.g.,
+// headlines). The returned tokens keep their original text.
+func WithCaseFoldForTagging(x bool) TaggerOptFunc {
+	return func(tagger *PerceptronTagger) {
+		tagger.caseFold = x
+	}
+}
+
+// WithBeamWidth makes the tagger keep the k best tag sequences at each step,
+// rather than greedily committing to the single best tag. A width of 1 (the
+// default) is equivalent to greedy tagging.
+//
+// Hypotheses are ranked by the sum of their tags' log probabilities (the
+// softmax of the model's scores). Each requires its own feature extraction
+// and prediction, so tagging is several times slower (about 6x for k=3; see
+// BenchmarkBeamWidth).
+//
+// The bundled model was trained greedily, so a wider beam isn't necessarily
+// more accurate: on the WSJ sample in the tests, k=2 through k=5 match greedy
+// tagging's accuracy (0.957) without improving on it. Ranking with the raw
+// scores instead lowered it to 0.946.
+func WithBeamWidth(k int) TaggerOptFunc {
+	return func(tagger *PerceptronTagger) {
+		tagger.beamWidth = k
+	}
+}
+
+// WithSkipTrivialTagging tags numbers (e.g., "42", "1,000", or "3.5") as CD
+// without consulting the model.
+//
+// Punctuation known to the model is always tagged from its tag map without
+// computing any features, so numbers are the only trivial tokens that would
+// otherwise require a full prediction.
+func WithSkipTrivialTagging(x bool) TaggerOptFunc {
+	return func(tagger *PerceptronTagger) {
+		tagger.trivial = x
+	}
+}
+
+// WithSuffixTagger tags out-of-vocabulary words -- i.e., words that the model
+// has no "i word" feature for -- using the most probable tag for their
+// longest suffix known to st (see ReadSuffixTagger). Words without a known
+// suffix are still tagged by the model.
+func WithSuffixTagger(st *SuffixTagger) TaggerOptFunc {
+	return func(tagger *PerceptronTagger) {
+		tagger.suffixes = st
+	}
+}
+
+// WithLemmatization populates each Token's Lemma field, using its tag to
+// choose between readings (e.g., "saw" is lemmatized as "see" when tagged VBD
+// but as "saw" when tagged NN). See Lemmatize for details.
+func WithLemmatization(x bool) TaggerOptFunc {
+	return func(tagger *PerceptronTagger) {
+		tagger.lemmas = x
+	}
 }
 
 // NewPerceptronTagger creates a new PerceptronTagger and loads the built-in
 // AveragedPerceptron model.
-func NewPerceptronTagger() *PerceptronTagger {
-	return &PerceptronTagger{model: NewAveragedPerceptron(wts, tags, classes)}
+func NewPerceptronTagger(opts ...TaggerOptFunc) *PerceptronTagger {
+	pt := &PerceptronTagger{model: NewAveragedPerceptron(wts, tags, classes)}
+	for _, applyOpt := range opts {
+		applyOpt(pt)
+	}
+	return pt
 }
 
 //	 Wts returns the model's weights in the form
@@ -97,12 +184,70 @@ func (pt *PerceptronTagger) TagMap() map[string]string {
 // Tag takes a slice of words and returns a slice of tagged tokens.
 func (pt *PerceptronTagger) Tag(words []string) []Token {
 	var tokens []Token
-	var clean []string
 	var tag string
 	var found bool
 
 	p1, p2 := "-START-", "-START2-"
-	context := []string{p1, p2}
+	clean, feats, context := pt.prepare(words)
+	if pt.beamWidth > 1 {
+		for i, tag := range pt.beamSearch(clean, feats, context) {
+			tokens = append(tokens, Token{Tag: tag, Text: clean[i]})
+		}
+	} else {
+		for i, word := range clean {
+			if tag, found = pt.fixedTag(word, feats[i]); !found {
+				tag = pt.model.predict(pt.featurize(i, context, feats[i], p1, p2))
+			}
+			tokens = append(tokens, Token{Tag: tag, Text: word})
+			p2 = p1
+			p1 = tag
+		}
+	}
+
+	if pt.smartCase {
+		normalizeCase(tokens)
+	}
+	if pt.lemmas {
+		lemmatize(tokens)
+	}
+
+	return tokens
+}
+
+// SetUnknownWordFeaturizer registers a function that supplies additional
+// features for out-of-vocabulary words -- i.e., words that the model has no
+// "i word" feature for.
+//
+// The returned strings are added to the built-in features (suffix, prefix,
+// and surrounding context) and weighted the same way: each occurrence counts
+// once, and a feature only contributes to a prediction if the model has
+// weights for it. Domain patterns should therefore map to existing features,
+// such as "i suffix ase" or "i pref1 X", or to features learned in training.
+func (pt *PerceptronTagger) SetUnknownWordFeaturizer(fn func(word string) []string) {
+	pt.unknown = fn
+}
+
+// featurize returns the features for the ith word, including those supplied
+// by the unknown-word featurizer.
+func (pt *PerceptronTagger) featurize(i int, ctx []string, w, p1, p2 string) map[string]float64 {
+	feats := featurize(i, ctx, w, p1, p2)
+	if pt.unknown == nil {
+		return feats
+	} else if _, known := pt.model.weights["i word "+ctx[i+2]]; known {
+		return feats
+	}
+	for _, feat := range pt.unknown(w) {
+		feats = add([]string{feat}, feats)
+	}
+	return feats
+}
+
+// prepare returns the non-empty words, the forms of those words used for
+// featurization, and their normalized context.
+func (pt *PerceptronTagger) prepare(words []string) ([]string, []string, []string) {
+	var clean []string
+
+	context := []string{"-START-", "-START2-"}
 	for _, w := range words {
 		if w == "" {
 			continue
@@ -111,20 +256,164 @@ func (pt *PerceptronTagger) Tag(words []string) []Token {
 		clean = append(clean, w)
 	}
 	context = append(context, []string{"-END-", "-END2-"}...)
-	for i, word := range clean {
-		if none.MatchString(word) {
-			tag = "-NONE-"
-		} else if keep.MatchString(word) {
-			tag = word
-		} else if tag, found = pt.model.tagMap[word]; !found {
-			tag = pt.model.predict(featurize(i, context, word, p1, p2))
+
+	feats := clean
+	if pt.caseFold && isUpper(clean) {
+		feats = make([]string, len(clean))
+		for i, word := range clean {
+			feats[i] = strings.ToLower(word)
 		}
-		tokens = append(tokens, Token{Tag: tag, Text: word})
-		p2 = p1
-		p1 = tag
 	}
 
-	return tokens
+	return clean, feats, context
+}
+
+// A FeatureScore is a feature's contribution to a predicted tag.
+type FeatureScore struct {
+	Feature string  // e.g., "i suffix ing"
+	Weight  float64 // the feature's value multiplied by its weight
+}
+
+// ExplainTag returns the features that contributed to the tag predicted for
+// the ith token of Tag(words), sorted from most to least supportive.
+//
+// It returns nil if i is out of range or if the tag didn't come from the
+// model -- e.g., a word in the tagger's tag map.
+func (pt *PerceptronTagger) ExplainTag(words []string, i int) []FeatureScore {
+	tokens := pt.Tag(words)
+	if i < 0 || i >= len(tokens) {
+		return nil
+	}
+
+	clean, feats, context := pt.prepare(words)
+	if _, found := pt.fixedTag(clean[i], feats[i]); found {
+		return nil
+	}
+
+	p1, p2 := "-START-", "-START2-"
+	if i > 0 {
+		p1 = tokens[i-1].Tag
+	}
+	if i > 1 {
+		p2 = tokens[i-2].Tag
+	} else if i == 1 {
+		p2 = "-START-"
+	}
+
+	scores := []FeatureScore{}
+	for feat, value := range pt.featurize(i, context, feats[i], p1, p2) {
+		if weight := pt.model.weights[feat][tokens[i].Tag]; weight != 0 && value != 0 {
+			scores = append(scores, FeatureScore{Feature: feat, Weight: value * weight})
+		}
+	}
+	sort.Slice(scores, func(a, b int) bool {
+		if scores[a].Weight != scores[b].Weight {
+			return scores[a].Weight > scores[b].Weight
+		}
+		return scores[a].Feature < scores[b].Feature
+	})
+
+	return scores
+}
+
+// fixedTag returns the tag of a word that doesn't require the model, if
+// any.
+func (pt *PerceptronTagger) fixedTag(word, feat string) (string, bool) {
+	if none.MatchString(word) {
+		return "-NONE-", true
+	} else if keep.MatchString(word) {
+		return word, true
+	} else if pt.trivial && number.MatchString(word) {
+		return "CD", true
+	}
+	if tag, found := pt.model.tagMap[feat]; found {
+		return tag, true
+	} else if pt.suffixes != nil {
+		if _, known := pt.model.weights["i word "+normalize(word)]; !known {
+			return pt.suffixes.Tag(feat)
+		}
+	}
+	return "", false
+}
+
+// A hypothesis is a partial tag sequence considered during beam search.
+type hypothesis struct {
+	tags  []string
+	score float64
+}
+
+// prev returns the hypothesis' last two tags.
+func (h hypothesis) prev() (string, string) {
+	p1, p2 := "-START-", "-START2-"
+	if n := len(h.tags); n > 1 {
+		p1, p2 = h.tags[n-1], h.tags[n-2]
+	} else if n == 1 {
+		p1, p2 = h.tags[0], "-START-"
+	}
+	return p1, p2
+}
+
+func (h hypothesis) extend(tag string, score float64) hypothesis {
+	tags := make([]string, len(h.tags), len(h.tags)+1)
+	copy(tags, h.tags)
+	return hypothesis{tags: append(tags, tag), score: h.score + score}
+}
+
+// beamSearch returns the highest-scoring tag sequence found by keeping the
+// pt.beamWidth best hypotheses at each step.
+func (pt *PerceptronTagger) beamSearch(words, feats, context []string) []string {
+	beam := []hypothesis{{}}
+	for i, word := range words {
+		candidates := []hypothesis{}
+		if tag, found := pt.fixedTag(word, feats[i]); found {
+			for _, h := range beam {
+				candidates = append(candidates, h.extend(tag, 0))
+			}
+		} else {
+			for _, h := range beam {
+				p1, p2 := h.prev()
+				scores := pt.model.scores(pt.featurize(i, context, feats[i], p1, p2))
+				if len(scores) == 0 {
+					candidates = append(candidates, h.extend("", 0))
+				}
+				for label, score := range logSoftmax(scores) {
+					candidates = append(candidates, h.extend(label, score))
+				}
+			}
+		}
+
+		sort.SliceStable(candidates, func(a, b int) bool {
+			if candidates[a].score != candidates[b].score {
+				return candidates[a].score > candidates[b].score
+			}
+			return strings.Join(candidates[a].tags, " ") < strings.Join(candidates[b].tags, " ")
+		})
+		beam = candidates[:internal.Min(len(candidates), pt.beamWidth)]
+	}
+	return beam[0].tags
+}
+
+// logSoftmax converts the model's raw scores into log probabilities, so that
+// they can be summed across a sentence: unnormalized, a large margin on one
+// word can outweigh several more confident predictions on the others.
+func logSoftmax(scores map[string]float64) map[string]float64 {
+	top := math.Inf(-1)
+	for _, score := range scores {
+		if score > top {
+			top = score
+		}
+	}
+
+	z := 0.0
+	for _, score := range scores {
+		z += math.Exp(score - top)
+	}
+
+	probs := make(map[string]float64, len(scores))
+	for label, score := range scores {
+		probs[label] = score - top - math.Log(z)
+	}
+	return probs
 }
 
 func (pt *PerceptronTagger) makeTagMap(sentences TupleSlice) {
@@ -144,12 +433,118 @@ func (pt *PerceptronTagger) makeTagMap(sentences TupleSlice) {
 		tag, mode := maxValue(tagFreqs)
 		n := float64(sumValues(tagFreqs))
 		if n >= 20 && (float64(mode)/n) >= 0.97 {
-			pt.tagMap[word] = tag
+			pt.model.tagMap[word] = tag
 		}
 	}
 }
 
+// TrainPerceptronTagger creates a new PerceptronTagger whose model is trained
+// from scratch on the given tagged sentences (see ReadTagged), making the
+// given number of passes over them.
+//
+// Sentences are shuffled between passes using a fixed seed, so training on
+// the same data always produces the same model.
+//
+// The given options only apply to the trained tagger: during training, only
+// words in the tag map (and the "-NONE-" and "-XXX-" placeholders) bypass the
+// model, so options like WithSuffixTagger and WithSkipTrivialTagging don't
+// keep it from learning the words they'd tag.
+func TrainPerceptronTagger(sentences TupleSlice, iterations int, opts ...TaggerOptFunc) *PerceptronTagger {
+	pt := &PerceptronTagger{model: NewAveragedPerceptron(
+		map[string]map[string]float64{}, map[string]string{}, []string{})}
+	pt.makeTagMap(sentences)
+
+	shuffled := append(TupleSlice{}, sentences...)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < iterations; i++ {
+		for _, tuple := range shuffled {
+			var words, tags []string
+			context := []string{"-START-", "-START2-"}
+			for j, w := range tuple[0] {
+				if w != "" {
+					words, tags = append(words, w), append(tags, tuple[1][j])
+					context = append(context, normalize(w))
+				}
+			}
+			context = append(context, "-END-", "-END2-")
+
+			p1, p2 := "-START-", "-START2-"
+			for j, word := range words {
+				guess, found := pt.fixedTag(word, word)
+				if !found {
+					feats := featurize(j, context, word, p1, p2)
+					guess = pt.model.best(feats)
+					pt.model.update(tags[j], guess, feats)
+				}
+				p2, p1 = p1, guess
+			}
+		}
+		rng.Shuffle(len(shuffled), shuffled.Swap)
+	}
+	pt.model.averageWeights()
+
+	for _, applyOpt := range opts {
+		applyOpt(pt)
+	}
+	return pt
+}
+
+// Write serializes the tagger's model (its weights, tag map, and classes) to
+// w, for use with ReadPerceptronTagger.
+func (pt *PerceptronTagger) Write(w io.Writer) error {
+	enc := gob.NewEncoder(w)
+	for _, v := range []any{pt.model.classes, pt.model.tagMap, pt.model.weights} {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadPerceptronTagger creates a new PerceptronTagger from a model written by
+// PerceptronTagger.Write.
+func ReadPerceptronTagger(r io.Reader, opts ...TaggerOptFunc) (*PerceptronTagger, error) {
+	var classes []string
+	var tags map[string]string
+	var weights map[string]map[string]float64
+
+	dec := gob.NewDecoder(r)
+	for _, v := range []any{&classes, &tags, &weights} {
+		if err := dec.Decode(v); err != nil {
+			return nil, err
+		}
+	}
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	if weights == nil {
+		weights = map[string]map[string]float64{}
+	}
+
+	pt := NewPerceptronTagger(opts...)
+	pt.model = NewAveragedPerceptron(weights, tags, classes)
+	return pt, nil
+}
+
 func (ap *AveragedPerceptron) predict(features map[string]float64) string {
+	return max(ap.scores(features))
+}
+
+// best returns the highest-scoring class for features, breaking ties by the
+// order of the model's classes. Unlike predict, it always returns a class.
+func (ap *AveragedPerceptron) best(features map[string]float64) string {
+	scores := ap.scores(features)
+
+	class, top := "", math.Inf(-1)
+	for _, c := range ap.classes {
+		if scores[c] > top {
+			class, top = c, scores[c]
+		}
+	}
+	return class
+}
+
+func (ap *AveragedPerceptron) scores(features map[string]float64) map[string]float64 {
 	var weights map[string]float64
 	var found bool
 
@@ -166,7 +561,7 @@ func (ap *AveragedPerceptron) predict(features map[string]float64) string {
 			}
 		}
 	}
-	return max(scores)
+	return scores
 }
 
 func (ap *AveragedPerceptron) update(truth, guess string, feats map[string]float64) {
@@ -186,13 +581,38 @@ func (ap *AveragedPerceptron) update(truth, guess string, feats map[string]float
 	}
 }
 
+// updateFeat adds w to the weight v of feature f for class c, first
+// accumulating the old weight's contribution to the running average.
 func (ap *AveragedPerceptron) updateFeat(c, f string, v, w float64) {
 	key := f + "-" + c
-	ap.totals[key] = (ap.instances - ap.stamps[key]) * w
+	ap.totals[key] += (ap.instances - ap.stamps[key]) * v
 	ap.stamps[key] = ap.instances
 	ap.weights[f][c] = w + v
 }
 
+// averageWeights replaces each weight with its average over all training
+// instances, dropping those that average to zero.
+func (ap *AveragedPerceptron) averageWeights() {
+	if ap.instances == 0 {
+		return
+	}
+	for f, weights := range ap.weights {
+		averaged := map[string]float64{}
+		for c, w := range weights {
+			key := f + "-" + c
+			total := ap.totals[key] + (ap.instances-ap.stamps[key])*w
+			if avg := math.Round(total/ap.instances*1000) / 1000; avg != 0 {
+				averaged[c] = avg
+			}
+		}
+		if len(averaged) > 0 {
+			ap.weights[f] = averaged
+		} else {
+			delete(ap.weights, f)
+		}
+	}
+}
+
 func (ap *AveragedPerceptron) addClass(class string) {
 	if !internal.StringInSlice(class, ap.classes) {
 		ap.classes = append(ap.classes, class)
@@ -259,6 +679,22 @@ func normalize(word string) string {
 	return strings.ToLower(word)
 }
 
+// isUpper determines if words contain at least one letter and no lowercase
+// letters.
+func isUpper(words []string) bool {
+	cased := false
+	for _, word := range words {
+		for _, r := range word {
+			if unicode.IsLower(r) {
+				return false
+			} else if unicode.IsUpper(r) {
+				cased = true
+			}
+		}
+	}
+	return cased
+}
+
 func sumValues(m map[string]int) int {
 	sum := 0
 	for _, v := range m {
@@ -1,7 +1,17 @@
 package tag
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jdkato/twine/internal"
+	"github.com/jdkato/twine/nlp/segment"
+	"github.com/jdkato/twine/nlp/tokenize"
 )
 
 var wsj = "Pierre|NNP Vinken|NNP ,|, 61|CD years|NNS old|JJ ,|, will|MD " +
@@ -23,3 +33,439 @@ func ExampleReadTagged() {
 	fmt.Println(ReadTagged(tagged, "|"))
 	// Output: [[[Pierre Vinken , 61 years] [NNP NNP , CD NNS]]]
 }
+
+func TestSmartCaseNormalization(t *testing.T) {
+	tagger := NewPerceptronTagger(WithSmartCaseNormalization(true))
+	cases := map[string][]string{
+		"Apple makes great phones .":     {"Apple", "makes", "great", "phones", "."},
+		"The apple fell from the tree .": {"the", "apple", "fell", "from", "the", "tree", "."},
+		`" Apple unveiled a phone .`:     {`"`, "Apple", "unveiled", "a", "phone", "."},
+	}
+	for sent, expected := range cases {
+		observed := []string{}
+		for _, tok := range tagger.Tag(strings.Fields(sent)) {
+			observed = append(observed, tok.Normal)
+		}
+		if !reflect.DeepEqual(observed, expected) {
+			t.Errorf("%q: got %q; expected %q", sent, observed, expected)
+		}
+	}
+
+	for _, tok := range NewPerceptronTagger().Tag([]string{"The", "apple"}) {
+		if tok.Normal != "" {
+			t.Errorf("%q: unexpected Normal %q", tok.Text, tok.Normal)
+		}
+	}
+}
+
+func accuracy(tagger *PerceptronTagger, sentences TupleSlice) float64 {
+	correct, total := 0.0, 0.0
+	for _, tuple := range sentences {
+		for i, tok := range tagger.Tag(tuple[0]) {
+			if tok.Tag == tuple[1][i] {
+				correct++
+			}
+			total++
+		}
+	}
+	return correct / total
+}
+
+func TestCaseFoldForTagging(t *testing.T) {
+	// The tags are already uppercase, so this only changes the words.
+	upper := ReadTagged(strings.ToUpper(wsj), "|")
+
+	before := accuracy(NewPerceptronTagger(), upper)
+	after := accuracy(NewPerceptronTagger(WithCaseFoldForTagging(true)), upper)
+	if after <= before {
+		t.Errorf("expected improved accuracy: before %0.2f; after %0.2f", before, after)
+	}
+
+	tagger := NewPerceptronTagger(WithCaseFoldForTagging(true))
+	if accuracy(tagger, ReadTagged(wsj, "|")) != accuracy(NewPerceptronTagger(), ReadTagged(wsj, "|")) {
+		t.Error("expected mixed-case accuracy to be unchanged")
+	}
+
+	for i, tok := range tagger.Tag(upper[0][0]) {
+		if tok.Text != upper[0][0][i] {
+			t.Errorf("got %q; expected %q", tok.Text, upper[0][0][i])
+		}
+	}
+}
+
+func TestBeamWidth(t *testing.T) {
+	sentences := ReadTagged(wsj, "|")
+
+	greedy := NewPerceptronTagger()
+	single := NewPerceptronTagger(WithBeamWidth(1))
+	for _, tuple := range sentences {
+		if !reflect.DeepEqual(greedy.Tag(tuple[0]), single.Tag(tuple[0])) {
+			t.Errorf("%q: expected k=1 to match greedy tagging", tuple[0])
+		}
+	}
+
+	beam := NewPerceptronTagger(WithBeamWidth(3))
+	for _, tuple := range sentences {
+		if n := len(beam.Tag(tuple[0])); n != len(tuple[0]) {
+			t.Errorf("got %d tokens; expected %d", n, len(tuple[0]))
+		}
+	}
+
+	baseline := accuracy(greedy, sentences)
+	for _, k := range []int{2, 3, 5} {
+		if acc := accuracy(NewPerceptronTagger(WithBeamWidth(k)), sentences); acc < baseline {
+			t.Errorf("k=%d: got accuracy %0.3f; expected at least greedy's %0.3f", k, acc, baseline)
+		}
+	}
+}
+
+func BenchmarkBeamWidth(b *testing.B) {
+	sentences := ReadTagged(wsj, "|")
+	for _, k := range []int{1, 3} {
+		tagger := NewPerceptronTagger(WithBeamWidth(k))
+		b.Run(fmt.Sprintf("k=%d", k), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				for _, tuple := range sentences {
+					tagger.Tag(tuple[0])
+				}
+			}
+		})
+	}
+}
+
+func TestUnknownWordFeaturizer(t *testing.T) {
+	words := strings.Fields("The brca1 gene is large .")
+
+	tagger := NewPerceptronTagger()
+	if tag := tagger.Tag(words)[1].Tag; tag != "NN" {
+		t.Fatalf("brca1: got %s; expected NN", tag)
+	}
+
+	seen := []string{}
+	tagger.SetUnknownWordFeaturizer(func(word string) []string {
+		seen = append(seen, word)
+		if strings.ContainsAny(word, "0123456789") {
+			// Treat gene names as though they were capitalized.
+			return []string{"i pref1 " + strings.ToUpper(word[:1])}
+		}
+		return nil
+	})
+
+	if tag := tagger.Tag(words)[1].Tag; tag != "NNP" {
+		t.Errorf("brca1: got %s; expected NNP", tag)
+	}
+
+	for _, word := range seen {
+		if word == "The" || word == "gene" {
+			t.Errorf("%s: expected only OOV words to be featurized", word)
+		}
+	}
+}
+
+var numeric = "In 1999 , 3,500 of 10,000 ( 35 % ) voted ; 12.5 % abstained , " +
+	"2 left , and 0.5 % -- 50 people -- spoiled 7 or 8 ballots ."
+
+func TestSkipTrivialTagging(t *testing.T) {
+	words := strings.Fields(numeric)
+	for _, tuple := range ReadTagged(wsj, "|") {
+		words = append(words, tuple[0]...)
+	}
+
+	expected := NewPerceptronTagger().Tag(words)
+	observed := NewPerceptronTagger(WithSkipTrivialTagging(true)).Tag(words)
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("got %v; expected %v", observed, expected)
+	}
+}
+
+func BenchmarkSkipTrivialTagging(b *testing.B) {
+	words := strings.Fields(strings.Repeat(numeric+" ", 20))
+	for _, skip := range []bool{false, true} {
+		tagger := NewPerceptronTagger(WithSkipTrivialTagging(skip))
+		b.Run(fmt.Sprintf("skip=%v", skip), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				tagger.Tag(words)
+			}
+		})
+	}
+}
+
+func TestExplainTag(t *testing.T) {
+	tagger := NewPerceptronTagger()
+	words := strings.Fields("The brca1 gene is large .")
+
+	scores := tagger.ExplainTag(words, 1)
+	if len(scores) == 0 {
+		t.Fatal("expected an explanation for brca1")
+	}
+
+	total := 0.0
+	for i, score := range scores {
+		total += score.Weight
+		if i > 0 && score.Weight > scores[i-1].Weight {
+			t.Errorf("expected sorted scores, got %v", scores)
+		}
+	}
+
+	// The chosen tag's total score must beat every other class.
+	tag := tagger.Tag(words)[1].Tag
+	_, _, ctx := tagger.prepare(words)
+	for class, score := range tagger.model.scores(featurize(1, ctx, "brca1", "DT", "-START-")) {
+		if class != tag && score > total {
+			t.Errorf("%s scored %0.2f, more than %s's %0.2f", class, score, tag, total)
+		}
+	}
+
+	if tagger.ExplainTag(words, 0) != nil {
+		t.Error("expected no explanation for a tag-map word")
+	}
+	if tagger.ExplainTag(words, len(words)) != nil {
+		t.Error("expected no explanation out of range")
+	}
+}
+
+func TestTokenHash(t *testing.T) {
+	// The hash is part of the API contract, so it must never change.
+	if h := (Token{Text: "The", Tag: "DT"}).Hash(); h != 0x9fccd15752bb5e4c {
+		t.Errorf("Hash: got %#x; expected %#x", h, uint64(0x9fccd15752bb5e4c))
+	}
+
+	same := Token{Text: "the", Tag: "DT", Normal: "the"}
+	if (Token{Text: "The", Tag: "DT"}).Hash() != same.Hash() {
+		t.Error("Hash: expected case-insensitive text")
+	}
+	if (Token{Text: "run", Tag: "VB"}).Hash() == (Token{Text: "run", Tag: "NN"}).Hash() {
+		t.Error("Hash: expected tags to differ")
+	}
+	if (Token{Text: "ab", Tag: "c"}).Hash() == (Token{Text: "a", Tag: "bc"}).Hash() {
+		t.Error("Hash: expected text and tag to be separated")
+	}
+}
+
+func TestSuffixTagger(t *testing.T) {
+	trie := `# suffix tag probability
+ase NN 0.95
+ase NNP 0.05
+ases NNS 0.97
+itis NN 0.99`
+	st, err := ReadSuffixTagger(strings.NewReader(trie))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	words := strings.Fields("Helicase unwinds DNA , and lipases digest fats .")
+	tagger := NewPerceptronTagger(WithSuffixTagger(st))
+
+	observed := []string{}
+	for _, tok := range tagger.Tag(words) {
+		observed = append(observed, tok.Tag)
+	}
+	expected := []string{"NN", "VBZ", "NNP", ",", "CC", "NNS", "VBP", "NNS", "."}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("WithSuffixTagger: got %v; expected %v", observed, expected)
+	}
+	if tag := NewPerceptronTagger().Tag(words)[0].Tag; tag != "NNP" {
+		t.Errorf("default: got %s for Helicase; expected NNP", tag)
+	}
+
+	for input, expected := range map[string]string{
+		"ase NN":        "line 1: expected 3 fields, got 2",
+		"\nase NN high": `line 2: invalid probability "high"`,
+	} {
+		if _, err := ReadSuffixTagger(strings.NewReader(input)); err == nil || err.Error() != expected {
+			t.Errorf("ReadSuffixTagger(%q): got %v; expected %q", input, err, expected)
+		}
+	}
+}
+
+func TestTrainPerceptronTagger(t *testing.T) {
+	sentences := ReadTagged(wsj, "|")
+
+	tagger := TrainPerceptronTagger(sentences, 5)
+	if acc := accuracy(tagger, sentences); acc < 0.99 {
+		t.Errorf("TrainPerceptronTagger: got a training accuracy of %.3f", acc)
+	}
+	if !reflect.DeepEqual(tagger.Weights(), TrainPerceptronTagger(sentences, 5).Weights()) {
+		t.Error("TrainPerceptronTagger: expected identical models from identical data")
+	}
+
+	var buf bytes.Buffer
+	if err := tagger.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadPerceptronTagger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	words := strings.Fields("Mr. Vinken is chairman of Elsevier , the Dutch group .")
+	if observed, expected := loaded.Tag(words), tagger.Tag(words); !reflect.DeepEqual(observed, expected) {
+		t.Errorf("ReadPerceptronTagger: got %v; expected %v", observed, expected)
+	}
+
+	if _, err := ReadPerceptronTagger(strings.NewReader("not a model")); err == nil {
+		t.Error("ReadPerceptronTagger: expected an error for invalid input")
+	}
+}
+
+func TestTrainPerceptronTaggerOptions(t *testing.T) {
+	st, err := ReadSuffixTagger(strings.NewReader("ase NN 0.9"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sentences TupleSlice
+	for i := 0; i < 3; i++ {
+		sentences = append(sentences, [][]string{
+			{"the", "lease", "expires", "in", "30", "days"},
+			{"DT", "VB", "VBZ", "IN", "CD", "NNS"}})
+	}
+
+	plain := TrainPerceptronTagger(sentences, 5)
+	tagger := TrainPerceptronTagger(sentences, 5, WithSuffixTagger(st), WithSkipTrivialTagging(true))
+	if !reflect.DeepEqual(tagger.Weights(), plain.Weights()) {
+		t.Error("TrainPerceptronTagger: expected options not to affect training")
+	}
+
+	words, tags := sentences[0][0], sentences[0][1]
+	for i, token := range tagger.Tag(words) {
+		if token.Tag != tags[i] {
+			t.Errorf("TrainPerceptronTagger: tagged %q as %s; expected %s", token.Text, token.Tag, tags[i])
+		}
+	}
+}
+
+func TestLemmatize(t *testing.T) {
+	cases := []struct{ word, tag, lemma string }{
+		{"saw", "VBD", "see"}, {"saw", "NN", "saw"}, {"left", "VBD", "leave"},
+		{"left", "VBP", "left"}, {"are", "VBP", "be"}, {"Is", "VBZ", "be"},
+		{"running", "VBG", "run"}, {"making", "VBG", "make"}, {"seeing", "VBG", "see"},
+		{"stopped", "VBD", "stop"}, {"hoped", "VBD", "hope"}, {"tried", "VBD", "try"},
+		{"agreed", "VBD", "agree"}, {"added", "VBD", "add"}, {"called", "VBD", "call"},
+		{"visited", "VBN", "visit"}, {"believed", "VBN", "believe"}, {"required", "VBN", "require"},
+		{"watches", "VBZ", "watch"}, {"uses", "VBZ", "use"}, {"cities", "NNS", "city"},
+		{"boxes", "NNS", "box"}, {"classes", "NNS", "class"}, {"children", "NNS", "child"},
+		{"buses", "NNS", "bus"}, {"quizzes", "NNS", "quiz"}, {"buzzes", "NNS", "buzz"},
+		{"gases", "NNS", "gas"}, {"houses", "NNS", "house"}, {"sizes", "NNS", "size"},
+		{"churches", "NNS", "church"}, {"wishes", "NNS", "wish"}, {"quizzes", "VBZ", "quiz"},
+		{"bigger", "JJR", "big"}, {"happiest", "JJS", "happy"}, {"larger", "JJR", "large"},
+		{"better", "JJR", "good"}, {"smaller", "JJR", "small"}, {"Apple", "NNP", "Apple"},
+		{"The", "DT", "the"}, {"relational", "", "relat"},
+	}
+	for _, c := range cases {
+		if lemma := Lemmatize(c.word, c.tag); lemma != c.lemma {
+			t.Errorf("Lemmatize(%q, %q): got %q; expected %q", c.word, c.tag, lemma, c.lemma)
+		}
+	}
+}
+
+func TestStem(t *testing.T) {
+	// Examples from Porter's paper.
+	cases := map[string]string{
+		"caresses": "caress", "ponies": "poni", "cats": "cat", "feed": "feed",
+		"agreed": "agre", "plastered": "plaster", "motoring": "motor",
+		"sing": "sing", "conflated": "conflat", "troubled": "troubl",
+		"sized": "size", "hopping": "hop", "falling": "fall", "filing": "file",
+		"happy": "happi", "sky": "sky", "relational": "relat",
+		"conditional": "condit", "digitizer": "digit", "vietnamization": "vietnam",
+		"hopefulness": "hope", "sensibiliti": "sensibl", "triplicate": "triplic",
+		"formative": "form", "goodness": "good", "revival": "reviv",
+		"adjustment": "adjust", "adoption": "adopt", "effective": "effect",
+		"probate": "probat", "rate": "rate", "cease": "ceas", "controll": "control",
+		"generalizations": "gener", "oscillators": "oscil",
+		"Caresses": "caress", "naïve": "naïve", "is": "is",
+	}
+	for word, expected := range cases {
+		if stem := Stem(word); stem != expected {
+			t.Errorf("Stem(%q): got %q; expected %q", word, stem, expected)
+		}
+	}
+}
+
+func TestLemmatization(t *testing.T) {
+	words := strings.Fields("I saw the saw .")
+	observed := []string{}
+	for _, tok := range NewPerceptronTagger(WithLemmatization(true)).Tag(words) {
+		observed = append(observed, tok.Lemma)
+	}
+	if expected := []string{"i", "see", "the", "saw", "."}; !reflect.DeepEqual(observed, expected) {
+		t.Errorf("got %q; expected %q", observed, expected)
+	}
+
+	for _, tok := range NewPerceptronTagger().Tag(words) {
+		if tok.Lemma != "" {
+			t.Errorf("%q: unexpected Lemma %q", tok.Text, tok.Lemma)
+		}
+	}
+}
+
+func TestTagReader(t *testing.T) {
+	paragraphs := []string{}
+	for _, tuple := range ReadTagged(wsj, "|") {
+		paragraphs = append(paragraphs, strings.Join(tuple[0], " "))
+	}
+	text := strings.TrimSpace(strings.Repeat(strings.Join(paragraphs, "\n\n")+"\n\n", 10))
+
+	tagger := NewPerceptronTagger()
+	tokenizer := tokenize.NewIterTokenizer()
+
+	expected := []TaggedSentence{}
+	for _, s := range segment.NewPunktSentenceTokenizer().Sentences(text) {
+		tokens := tokenizer.TokenizeWithOffsets(s.Text)
+		words := []string{}
+		for _, tok := range tokens {
+			tok.Start += s.Start
+			tok.End += s.Start
+			words = append(words, tok.Text)
+		}
+		expected = append(expected, TaggedSentence{Sentence: s, Tokens: tokens, Tags: tagger.Tag(words)})
+	}
+
+	read := func(text string, opts ...StreamOptFunc) ([]TaggedSentence, error) {
+		actual := []TaggedSentence{}
+		err := tagger.TagReader(strings.NewReader(text), func(ts TaggedSentence) error {
+			actual = append(actual, ts)
+			return nil
+		}, opts...)
+		return actual, err
+	}
+
+	for _, n := range []int{1, 3, 8} {
+		if actual, err := read(text, WithStreamWorkers(n)); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("TagReader(%d workers): got a different result than the non-streaming path", n)
+		}
+	}
+
+	article := string(internal.ReadDataFile(filepath.Join("..", "..", "testdata", "article.txt")))
+	single, err := read(article)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ts := range single {
+		if article[ts.Start:ts.End] != ts.Text || len(ts.Tags) != len(ts.Tokens) {
+			t.Errorf("TagReader: %q has offsets [%d:%d] and %d tags for %d tokens",
+				ts.Text, ts.Start, ts.End, len(ts.Tags), len(ts.Tokens))
+		}
+	}
+	if concurrent, err := read(article, WithStreamWorkers(4)); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(concurrent, single) {
+		t.Error("TagReader: expected the same result from 1 and 4 workers")
+	}
+
+	stop := errors.New("stop")
+	for _, n := range []int{1, 4} {
+		count := 0
+		err := tagger.TagReader(strings.NewReader(text), func(ts TaggedSentence) error {
+			if count++; count == 2 {
+				return stop
+			}
+			return nil
+		}, WithStreamWorkers(n))
+		if err != stop || count != 2 {
+			t.Errorf("TagReader(%d workers): got %v after %d sentences; expected stop after 2", n, err, count)
+		}
+	}
+}
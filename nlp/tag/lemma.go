@@ -0,0 +1,224 @@
+package tag
+
+import "strings"
+
+// nounExceptions maps plural nouns that the suffix rules in Lemmatize would
+// get wrong to their singular forms.
+var nounExceptions = map[string]string{
+	"analyses": "analysis", "calories": "calorie", "children": "child",
+	"cookies": "cookie", "crises": "crisis", "data": "datum", "echoes": "echo",
+	"feet": "foot", "geese": "goose", "halves": "half", "heroes": "hero",
+	"knives": "knife", "leaves": "leaf", "lives": "life", "men": "man",
+	"mice": "mouse", "movies": "movie", "oxen": "ox", "people": "person",
+	"potatoes": "potato", "selves": "self", "series": "series", "shelves": "shelf",
+	"species": "species", "teeth": "tooth", "theses": "thesis", "thieves": "thief",
+	"tomatoes": "tomato", "wives": "wife", "wolves": "wolf", "women": "woman",
+}
+
+// sibilants are nouns (and verbs), ending in -s or -z, that form their
+// plurals with -es (e.g., "buses" and "quizzes") -- as opposed to those that
+// end in a silent e (e.g., "houses" and "sizes").
+var sibilants = map[string]bool{
+	"alias": true, "atlas": true, "bias": true, "bonus": true, "bus": true,
+	"cactus": true, "campus": true, "canvas": true, "census": true,
+	"chorus": true, "circus": true, "consensus": true, "gas": true,
+	"genius": true, "iris": true, "lens": true, "minus": true, "octopus": true,
+	"plus": true, "quiz": true, "sinus": true, "status": true, "surplus": true,
+	"syllabus": true, "virus": true, "walrus": true, "whiz": true,
+}
+
+// verbExceptions maps the inflected forms of irregular verbs (and of regular
+// verbs that the suffix rules in Lemmatize would get wrong) to their base
+// forms.
+var verbExceptions = map[string]string{
+	"'s": "be", "is": "be", "was": "be", "were": "be", "been": "be",
+	"being": "be", "has": "have", "had": "have", "having": "have", "does": "do",
+	"did": "do", "done": "do", "goes": "go", "went": "go", "gone": "go",
+	"arranged": "arrange", "arranging": "arrange", "ate": "eat",
+	"became": "become", "began": "begin", "beginning": "begin",
+	"begun": "begin", "bought": "buy", "broke": "break", "broken": "break",
+	"brought": "bring", "built": "build", "came": "come",
+	"caught": "catch", "challenged": "challenge", "challenging": "challenge",
+	"changed": "change", "changing": "change", "chose": "choose",
+	"chosen": "choose", "created": "create", "creating": "create",
+	"dealt": "deal", "died": "die", "dies": "die", "drawn": "draw",
+	"drew": "draw", "driven": "drive", "drove": "drive", "dying": "die",
+	"eaten": "eat", "fallen": "fall", "fell": "fall", "felt": "feel",
+	"flew": "fly", "flown": "fly", "focused": "focus", "focuses": "focus",
+	"focusing": "focus", "forgot": "forget", "forgotten": "forget",
+	"fought": "fight", "found": "find", "gave": "give", "given": "give",
+	"got": "get", "gotten": "get", "grew": "grow", "grown": "grow",
+	"guided": "guide", "guiding": "guide", "heard": "hear", "held": "hold",
+	"kept": "keep", "knew": "know", "known": "know", "led": "lead",
+	"left": "leave", "lied": "lie", "lies": "lie", "lost": "lose",
+	"lying": "lie", "made": "make", "meant": "mean", "met": "meet",
+	"paid": "pay", "ran": "run", "risen": "rise", "rose": "rise", "said": "say",
+	"sang": "sing", "sat": "sit", "saw": "see", "seen": "see", "sent": "send",
+	"shot": "shoot", "sold": "sell", "sought": "seek", "spent": "spend",
+	"spoke": "speak", "spoken": "speak", "stood": "stand", "sung": "sing",
+	"taken": "take", "taught": "teach", "thought": "think", "threw": "throw",
+	"thrown": "throw", "tied": "tie", "ties": "tie", "told": "tell",
+	"took": "take", "tying": "tie", "understood": "understand", "won": "win",
+	"wore": "wear", "worn": "wear", "written": "write", "wrote": "write",
+}
+
+// presentExceptions maps the irregular non-third-person present forms (VBP)
+// to their base forms. They're kept apart from verbExceptions because some
+// of those are also base forms (e.g., "found" and "left").
+var presentExceptions = map[string]string{
+	"am": "be", "are": "be", "'m": "be", "'re": "be", "'ve": "have",
+}
+
+// adjectiveExceptions maps irregular comparatives and superlatives to their
+// positive forms.
+var adjectiveExceptions = map[string]string{
+	"better": "good", "best": "good",
+	"worse": "bad", "worst": "bad",
+	"elder": "old", "eldest": "old",
+	"farther": "far", "farthest": "far", "further": "far", "furthest": "far",
+	"less": "little", "lesser": "little", "least": "little",
+}
+
+// Lemmatize returns the dictionary form of word, given its part-of-speech
+// tag: plural nouns are singularized ("cities" -> "city"), verbs are reduced
+// to their base forms ("saw"/VBD -> "see"), and comparative and superlative
+// adjectives and adverbs to their positive forms ("bigger" -> "big").
+//
+// Irregular forms are looked up in a list of common exceptions, while
+// regular ones are handled by suffix rules; since there's no dictionary to
+// check them against, rarer words may be lemmatized incorrectly. Proper
+// nouns are returned as-is, words with any other tag are lowercased, and
+// untagged words (i.e., those with an empty tag) are stemmed (see Stem).
+func Lemmatize(word, tag string) string {
+	lower := strings.ToLower(word)
+	switch tag {
+	case "":
+		return Stem(word)
+	case "NNP", "NNPS":
+		return word
+	case "NNS":
+		if lemma, found := nounExceptions[lower]; found {
+			return lemma
+		}
+		return singularize(lower)
+	case "VB", "VBP":
+		if lemma, found := presentExceptions[lower]; found {
+			return lemma
+		}
+	case "VBZ", "VBD", "VBN", "VBG":
+		if lemma, found := verbExceptions[lower]; found {
+			return lemma
+		} else if tag == "VBZ" {
+			return singularize(lower)
+		} else if tag == "VBG" {
+			return unsuffix(lower, "ing")
+		}
+		if strings.HasSuffix(lower, "ied") && len(lower) > 4 {
+			return lower[:len(lower)-3] + "y"
+		} else if strings.HasSuffix(lower, "eed") {
+			return lower[:len(lower)-1]
+		}
+		return unsuffix(lower, "ed")
+	case "JJR", "JJS", "RBR", "RBS":
+		if lemma, found := adjectiveExceptions[lower]; found {
+			return lemma
+		}
+		return positive(lower)
+	}
+	return lower
+}
+
+// lemmatize sets the Lemma field of each token.
+func lemmatize(tokens []Token) {
+	for i := range tokens {
+		tokens[i].Lemma = Lemmatize(tokens[i].Text, tokens[i].Tag)
+	}
+}
+
+// singularize removes the -s or -es from a plural noun or a third-person
+// singular verb.
+func singularize(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "ses"), strings.HasSuffix(word, "zes"):
+		if sibilants[word[:len(word)-2]] {
+			return word[:len(word)-2]
+		} else if sibilants[word[:len(word)-3]] {
+			// The s or z was doubled (e.g., "quizzes").
+			return word[:len(word)-3]
+		} else if strings.HasSuffix(word, "sses") || strings.HasSuffix(word, "zzes") {
+			return word[:len(word)-2]
+		}
+		return word[:len(word)-1]
+	case strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "ches"),
+		strings.HasSuffix(word, "xes"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"),
+		strings.HasSuffix(word, "is"):
+		return word
+	case strings.HasSuffix(word, "s") && len(word) > 1:
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// positive removes the -er or -est from a comparative or superlative.
+func positive(word string) string {
+	var stem string
+	if strings.HasSuffix(word, "est") && len(word) > 4 {
+		stem = word[:len(word)-3]
+	} else if strings.HasSuffix(word, "er") && len(word) > 3 {
+		stem = word[:len(word)-2]
+	} else {
+		return word
+	}
+	if strings.HasSuffix(stem, "i") {
+		return stem[:len(stem)-1] + "y"
+	}
+	return restore(stem)
+}
+
+// unsuffix removes suffix (-ed or -ing) from word, as long as what's left
+// contains a vowel (e.g., "hoping" but not "bring").
+func unsuffix(word, suffix string) string {
+	stem := strings.TrimSuffix(word, suffix)
+	if len(stem) == len(word) || len(stem) < 2 || !strings.ContainsAny(stem, "aeiouy") {
+		return word
+	}
+	return restore(stem)
+}
+
+// restore undoes the spelling changes made when a suffix was added to stem:
+// it undoubles a final consonant ("stopp" -> "stop") or, using a few
+// heuristics, adds back a silent e ("hop" -> "hope" and "believ" ->
+// "believe").
+func restore(stem string) string {
+	n := len(stem)
+	last, prev := stem[n-1], stem[n-2]
+
+	if last == prev && !isVowel(last) {
+		if n > 3 && last != 'l' && last != 's' && last != 'z' {
+			return stem[:n-1]
+		}
+		return stem
+	}
+
+	s := &stemmer{b: []byte(stem), j: n - 1, k: n - 1}
+	switch {
+	case last == 'c' || last == 'u' || last == 'v' || last == 's' || last == 'z':
+	case last == 'l' && !isVowel(prev) && prev != 'r' && prev != 'w':
+	case last == 'g' && !isVowel(prev) && prev != 'n':
+	case last == 't' && prev == 'a' && (n < 3 || !strings.ContainsRune("aeo", rune(stem[n-3]))):
+	case n > 2 && (!isVowel(stem[n-3]) || strings.HasSuffix(stem[:n-2], "qu")) && strings.Contains("ar ib id in ir ok ud ur ut", stem[n-2:]):
+	case n == 2 && isVowel(prev):
+	case s.m() == 1 && s.cvc(n-1):
+	default:
+		return stem
+	}
+	return stem + "e"
+}
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiou", c) >= 0
+}
@@ -0,0 +1,250 @@
+package tag
+
+import "strings"
+
+// Stem returns the Porter stem of word (e.g., "relational" -> "relat"),
+// lowercasing it first. Words that contain anything other than ASCII letters,
+// or that are shorter than three letters, are only lowercased.
+//
+// See https://tartarus.org/martin/PorterStemmer/def.txt.
+func Stem(word string) string {
+	word = strings.ToLower(word)
+	if len(word) < 3 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	s := &stemmer{b: []byte(word), k: len(word) - 1}
+	s.step1ab()
+	if s.k > 0 {
+		s.step1c()
+		s.step2()
+		s.step3()
+		s.step4()
+		s.step5()
+	}
+	return string(s.b[:s.k+1])
+}
+
+// A stemmer holds the state of the Porter algorithm: the word being stemmed
+// is b[0:k+1], and j marks the end of the stem matched by the last call to
+// ends.
+type stemmer struct {
+	b    []byte
+	j, k int
+}
+
+// cons determines if b[i] is a consonant.
+func (s *stemmer) cons(i int) bool {
+	switch s.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !s.cons(i-1)
+	}
+	return true
+}
+
+// m measures the number of vowel-consonant sequences in b[0:j+1].
+func (s *stemmer) m() int {
+	n, i := 0, 0
+	for ; i <= s.j && s.cons(i); i++ {
+	}
+	for i <= s.j {
+		for ; i <= s.j && !s.cons(i); i++ {
+		}
+		if i > s.j {
+			break
+		}
+		n++
+		for ; i <= s.j && s.cons(i); i++ {
+		}
+	}
+	return n
+}
+
+// vowelInStem determines if b[0:j+1] contains a vowel.
+func (s *stemmer) vowelInStem() bool {
+	for i := 0; i <= s.j; i++ {
+		if !s.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doublec determines if b[i-1:i+1] is a double consonant.
+func (s *stemmer) doublec(i int) bool {
+	return i >= 1 && s.b[i] == s.b[i-1] && s.cons(i)
+}
+
+// cvc determines if b[i-2:i+1] is consonant-vowel-consonant, where the final
+// consonant isn't w, x, or y (e.g., "hop" but not "bow").
+func (s *stemmer) cvc(i int) bool {
+	if i < 2 || !s.cons(i) || s.cons(i-1) || !s.cons(i-2) {
+		return false
+	}
+	c := s.b[i]
+	return c != 'w' && c != 'x' && c != 'y'
+}
+
+// ends determines if b[0:k+1] ends with suffix, setting j accordingly.
+func (s *stemmer) ends(suffix string) bool {
+	n := len(suffix)
+	if n > s.k+1 || string(s.b[s.k-n+1:s.k+1]) != suffix {
+		return false
+	}
+	s.j = s.k - n
+	return true
+}
+
+// setTo replaces b[j+1:k+1] with suffix.
+func (s *stemmer) setTo(suffix string) {
+	s.b = append(s.b[:s.j+1], suffix...)
+	s.k = s.j + len(suffix)
+}
+
+// r replaces the matched suffix if the stem's measure is positive.
+func (s *stemmer) r(suffix string) {
+	if s.m() > 0 {
+		s.setTo(suffix)
+	}
+}
+
+// replace applies the first of the given (suffix, replacement) pairs whose
+// suffix matches, using r.
+func (s *stemmer) replace(pairs ...string) {
+	for i := 0; i < len(pairs); i += 2 {
+		if s.ends(pairs[i]) {
+			s.r(pairs[i+1])
+			return
+		}
+	}
+}
+
+// step1ab removes plurals and -ed or -ing (e.g., "caresses" -> "caress" and
+// "hopping" -> "hop").
+func (s *stemmer) step1ab() {
+	if s.b[s.k] == 's' {
+		if s.ends("sses") {
+			s.k -= 2
+		} else if s.ends("ies") {
+			s.setTo("i")
+		} else if s.b[s.k-1] != 's' {
+			s.k--
+		}
+	}
+
+	if s.ends("eed") {
+		if s.m() > 0 {
+			s.k--
+		}
+	} else if (s.ends("ed") || s.ends("ing")) && s.vowelInStem() {
+		s.k = s.j
+		if s.ends("at") {
+			s.setTo("ate")
+		} else if s.ends("bl") {
+			s.setTo("ble")
+		} else if s.ends("iz") {
+			s.setTo("ize")
+		} else if s.doublec(s.k) {
+			if c := s.b[s.k]; c != 'l' && c != 's' && c != 'z' {
+				s.k--
+			}
+		} else if s.j = s.k; s.m() == 1 && s.cvc(s.k) {
+			s.setTo("e")
+		}
+	}
+}
+
+// step1c turns a terminal y into i when there's another vowel in the stem.
+func (s *stemmer) step1c() {
+	if s.ends("y") && s.vowelInStem() {
+		s.b[s.k] = 'i'
+	}
+}
+
+// step2 maps double suffixes to single ones (e.g., "-ization" -> "-ize").
+func (s *stemmer) step2() {
+	switch s.b[s.k-1] {
+	case 'a':
+		s.replace("ational", "ate", "tional", "tion")
+	case 'c':
+		s.replace("enci", "ence", "anci", "ance")
+	case 'e':
+		s.replace("izer", "ize")
+	case 'l':
+		s.replace("bli", "ble", "alli", "al", "entli", "ent", "eli", "e", "ousli", "ous")
+	case 'o':
+		s.replace("ization", "ize", "ation", "ate", "ator", "ate")
+	case 's':
+		s.replace("alism", "al", "iveness", "ive", "fulness", "ful", "ousness", "ous")
+	case 't':
+		s.replace("aliti", "al", "iviti", "ive", "biliti", "ble")
+	case 'g':
+		s.replace("logi", "log")
+	}
+}
+
+// step3 handles -ic-, -full, -ness, etc.
+func (s *stemmer) step3() {
+	switch s.b[s.k] {
+	case 'e':
+		s.replace("icate", "ic", "ative", "", "alize", "al")
+	case 'i':
+		s.replace("iciti", "ic")
+	case 'l':
+		s.replace("ical", "ic", "ful", "")
+	case 's':
+		s.replace("ness", "")
+	}
+}
+
+// step4Suffixes are the suffixes step4 removes, keyed by their penultimate
+// letter.
+var step4Suffixes = map[byte][]string{
+	'a': {"al"},
+	'c': {"ance", "ence"},
+	'e': {"er"},
+	'i': {"ic"},
+	'l': {"able", "ible"},
+	'n': {"ant", "ement", "ment", "ent"},
+	'o': {"ion", "ou"},
+	's': {"ism"},
+	't': {"ate", "iti"},
+	'u': {"ous"},
+	'v': {"ive"},
+	'z': {"ize"},
+}
+
+// step4 removes -ant, -ence, etc. from stems with a measure above one.
+func (s *stemmer) step4() {
+	for _, suffix := range step4Suffixes[s.b[s.k-1]] {
+		if !s.ends(suffix) {
+			continue
+		} else if suffix == "ion" && (s.j < 0 || (s.b[s.j] != 's' && s.b[s.j] != 't')) {
+			return
+		}
+		if s.m() > 1 {
+			s.k = s.j
+		}
+		return
+	}
+}
+
+// step5 removes a final -e and undoubles a final -ll from long stems.
+func (s *stemmer) step5() {
+	s.j = s.k
+	if s.b[s.k] == 'e' {
+		if a := s.m(); a > 1 || (a == 1 && !s.cvc(s.k-1)) {
+			s.k--
+		}
+	}
+	if s.b[s.k] == 'l' && s.doublec(s.k) && s.m() > 1 {
+		s.k--
+	}
+}
@@ -0,0 +1,173 @@
+package tag
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/jdkato/twine/nlp/segment"
+	"github.com/jdkato/twine/nlp/tokenize"
+)
+
+// A TaggedSentence is a sentence streamed by TagReader.
+type TaggedSentence struct {
+	segment.Sentence
+
+	// Tokens are the sentence's tokens, whose offsets (like the sentence's)
+	// are relative to the start of the reader.
+	Tokens []*tokenize.Token
+	// Tags holds the tagged form of each of the sentence's tokens, in order.
+	Tags []Token
+}
+
+// A SentenceReader incrementally segments text, as
+// segment.NewPunktSentenceTokenizer's SegmentReader method does.
+type SentenceReader interface {
+	SegmentReader(r io.Reader, fn func(segment.Sentence) error) error
+}
+
+type stream struct {
+	segmenter SentenceReader
+	tokenizer tokenize.Tokenizer
+	workers   int
+}
+
+type StreamOptFunc func(*stream)
+
+// UsingStreamSegmenter segments the streamed text with s. The default is
+// segment.NewPunktSentenceTokenizer().
+func UsingStreamSegmenter(s SentenceReader) StreamOptFunc {
+	return func(st *stream) {
+		st.segmenter = s
+	}
+}
+
+// UsingStreamTokenizer tokenizes each streamed sentence with t, whose tokens'
+// offsets must be relative to the sentence. The default is
+// tokenize.NewOffsetTokenizer(tokenize.NewIterTokenizer()).
+func UsingStreamTokenizer(t tokenize.Tokenizer) StreamOptFunc {
+	return func(st *stream) {
+		st.tokenizer = t
+	}
+}
+
+// WithStreamWorkers tokenizes and tags up to n sentences concurrently. The
+// sentences are still passed to TagReader's callback in order, and no more
+// than n are held in memory while waiting for it.
+func WithStreamWorkers(n int) StreamOptFunc {
+	return func(st *stream) {
+		st.workers = n
+	}
+}
+
+// errStopped stops a SentenceReader once TagReader's callback has failed.
+var errStopped = errors.New("stream stopped")
+
+// TagReader incrementally segments, tokenizes, and tags the text read from r,
+// calling fn with each TaggedSentence in order. It stops at the first error
+// returned by fn or encountered while reading; EOF isn't an error.
+//
+// Memory use is bounded as described by segment's SegmentReader (i.e., by
+// the longest paragraph) plus the sentences being tagged (see
+// WithStreamWorkers). The tagger must not be modified (e.g., by
+// SetUnknownWordFeaturizer) while TagReader is running.
+func (pt *PerceptronTagger) TagReader(r io.Reader, fn func(TaggedSentence) error, opts ...StreamOptFunc) error {
+	st := &stream{workers: 1}
+	for _, applyOpt := range opts {
+		applyOpt(st)
+	}
+	if st.segmenter == nil {
+		st.segmenter = segment.NewPunktSentenceTokenizer()
+	}
+	if st.tokenizer == nil {
+		st.tokenizer = tokenize.NewOffsetTokenizer(tokenize.NewIterTokenizer())
+	}
+
+	if st.workers <= 1 {
+		return st.segmenter.SegmentReader(r, func(s segment.Sentence) error {
+			return fn(pt.tagSentence(st.tokenizer, s))
+		})
+	}
+	return pt.tagConcurrently(st, r, fn)
+}
+
+// tagSentence tokenizes and tags s.
+func (pt *PerceptronTagger) tagSentence(t tokenize.Tokenizer, s segment.Sentence) TaggedSentence {
+	tokens := t.Tokenize(s.Text)
+
+	words := make([]string, len(tokens))
+	for i, tok := range tokens {
+		tok.Start += s.Start
+		tok.End += s.Start
+		words[i] = tok.Text
+	}
+
+	return TaggedSentence{Sentence: s, Tokens: tokens, Tags: pt.Tag(words)}
+}
+
+// A job is a sentence waiting to be tagged by one of tagConcurrently's
+// workers, which sends the result to out.
+type job struct {
+	sentence segment.Sentence
+	out      chan TaggedSentence
+}
+
+// tagConcurrently implements TagReader using st.workers workers.
+//
+// Each sentence's result channel is queued, in order, on pending -- whose
+// capacity bounds the number of sentences in flight -- before the sentence
+// itself is handed to the workers; fn is then called with each result in
+// the order that the channels were queued.
+func (pt *PerceptronTagger) tagConcurrently(st *stream, r io.Reader, fn func(TaggedSentence) error) error {
+	jobs := make(chan job)
+	pending := make(chan chan TaggedSentence, st.workers)
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < st.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				j.out <- pt.tagSentence(st.tokenizer, j.sentence)
+			}
+		}()
+	}
+
+	var readErr error
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		readErr = st.segmenter.SegmentReader(r, func(s segment.Sentence) error {
+			out := make(chan TaggedSentence, 1)
+			select {
+			case <-done:
+				return errStopped
+			default:
+			}
+			select {
+			case pending <- out:
+			case <-done:
+				return errStopped
+			}
+			jobs <- job{sentence: s, out: out}
+			return nil
+		})
+	}()
+
+	var err error
+	for out := range pending {
+		ts := <-out
+		if err == nil {
+			if err = fn(ts); err != nil {
+				close(done)
+			}
+		}
+	}
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	return readErr
+}
@@ -0,0 +1,88 @@
+package tag
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A SuffixTagger guesses the tags of unknown words from their suffixes.
+type SuffixTagger struct {
+	tags    map[string]map[string]float64 // [suffix][tag]probability
+	longest int                           // the longest suffix, in runes
+}
+
+// ReadSuffixTagger reads a SuffixTagger from lines in the form
+//
+//	suffix tag probability
+//
+// (e.g., "ase NN 0.93"), ignoring blank lines and those starting with "#".
+// Suffixes are matched case-insensitively.
+func ReadSuffixTagger(r io.Reader) (*SuffixTagger, error) {
+	st := &SuffixTagger{tags: map[string]map[string]float64{}}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected 3 fields, got %d", line, len(fields))
+		}
+		p, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil || p < 0 || p > 1 {
+			return nil, fmt.Errorf("line %d: invalid probability %q", line, fields[2])
+		}
+
+		suffix := strings.ToLower(fields[0])
+		if st.tags[suffix] == nil {
+			st.tags[suffix] = map[string]float64{}
+		}
+		st.tags[suffix][fields[1]] = p
+		if n := utf8.RuneCountInString(suffix); n > st.longest {
+			st.longest = n
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return st, nil
+}
+
+// Tag returns the most probable tag for word's longest known suffix, if any.
+func (st *SuffixTagger) Tag(word string) (string, bool) {
+	word = strings.ToLower(word)
+
+	runes := []rune(word)
+	for n := st.longest; n > 0; n-- {
+		if n > len(runes) {
+			continue
+		}
+		probs, found := st.tags[string(runes[len(runes)-n:])]
+		if !found {
+			continue
+		}
+
+		candidates := make([]string, 0, len(probs))
+		for tag := range probs {
+			candidates = append(candidates, tag)
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			if probs[candidates[i]] != probs[candidates[j]] {
+				return probs[candidates[i]] > probs[candidates[j]]
+			}
+			return candidates[i] < candidates[j]
+		})
+		return candidates[0], true
+	}
+
+	return "", false
+}
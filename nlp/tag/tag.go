@@ -3,12 +3,32 @@ Package tag implements functions for tagging parts of speech.
 */
 package tag
 
-import "strings"
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
 
 // Token represents a tagged section of text.
 type Token struct {
-	Text string
-	Tag  string
+	Text   string // The token's text.
+	Tag    string // The token's part-of-speech tag (e.g., "NN").
+	Normal string // The case-normalized text (see WithSmartCaseNormalization).
+	Lemma  string // The dictionary form of the text (see WithLemmatization).
+}
+
+// Hash returns a stable 64-bit hash of t, suitable for use as a cache key.
+//
+// It's the FNV-1a hash of t's lowercased Text, a zero byte, and its Tag, so
+// it's the same across runs and machines. Normal isn't included: tokens that
+// only differ in case (or in whether they've been case-normalized) and share
+// a tag have the same hash.
+func (t Token) Hash() uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(strings.ToLower(t.Text)))
+	h.Write([]byte{0})
+	h.Write([]byte(t.Tag))
+	return h.Sum64()
 }
 
 // TupleSlice is a slice of tuples in the form (words, tags).
@@ -35,3 +55,20 @@ func ReadTagged(text, sep string) TupleSlice {
 	}
 	return t
 }
+
+// normalizeCase sets the Normal field of each token, lowercasing the first
+// word of the sentence unless it's a proper noun (e.g., "Apple" the company
+// vs. "apple" the fruit).
+func normalizeCase(tokens []Token) {
+	initial := true
+	for i := range tokens {
+		tokens[i].Normal = tokens[i].Text
+		if !initial || strings.IndexFunc(tokens[i].Text, unicode.IsLetter) < 0 {
+			continue
+		}
+		if tokens[i].Tag != "NNP" && tokens[i].Tag != "NNPS" {
+			tokens[i].Normal = strings.ToLower(tokens[i].Text)
+		}
+		initial = false
+	}
+}
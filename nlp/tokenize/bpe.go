@@ -0,0 +1,130 @@
+package tokenize
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// bpeTokenizer splits text into subword units using a byte-pair encoding
+// (BPE) merge table.
+type bpeTokenizer struct {
+	ranks map[[2]string]int
+	vocab map[string]bool
+}
+
+// A symbol is a (possibly merged) piece of a word, along with the byte
+// offset at which it starts.
+type symbol struct {
+	text  string
+	start int
+}
+
+var bpeWordRE = regexp.MustCompile(`[\p{L}\p{N}_]+|[^\p{L}\p{N}_\s]+`)
+
+// NewBPETokenizer creates a Tokenizer from a BPE merge table and vocabulary.
+//
+// merges is read line-by-line, with each line holding a pair of symbols
+// separated by a space (e.g., "t h"); earlier lines take precedence. Blank
+// lines and lines starting with "#" (such as a "#version" header) are
+// ignored. vocab holds one subword per line; a merge is only applied if its
+// result is in the vocabulary. If vocab is nil, every merge is allowed.
+//
+// Text is first split into runs of letters and digits and runs of other
+// non-space characters, each of which is then split into subwords. Every
+// subword after the first in a run is marked as a continuation.
+func NewBPETokenizer(merges io.Reader, vocab io.Reader) (Tokenizer, error) {
+	t := &bpeTokenizer{ranks: map[[2]string]int{}}
+
+	scanner := bufio.NewScanner(merges)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		pair := strings.Fields(text)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("merges: line %d: expected 2 symbols, got %d", line, len(pair))
+		}
+		key := [2]string{pair[0], pair[1]}
+		if _, found := t.ranks[key]; !found {
+			t.ranks[key] = len(t.ranks)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("merges: %w", err)
+	}
+
+	if vocab != nil {
+		t.vocab = map[string]bool{}
+		scanner = bufio.NewScanner(vocab)
+		for scanner.Scan() {
+			if text := strings.TrimSpace(scanner.Text()); text != "" {
+				t.vocab[text] = true
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("vocab: %w", err)
+		}
+	}
+
+	return t, nil
+}
+
+// Tokenize splits text into a slice of subword tokens.
+func (t *bpeTokenizer) Tokenize(text string) []*Token {
+	tokens := []*Token{}
+	for _, span := range bpeWordRE.FindAllStringIndex(text, -1) {
+		for i, sym := range t.encode(text[span[0]:span[1]]) {
+			start := span[0] + sym.start
+			tokens = append(tokens, &Token{
+				Text:         sym.text,
+				Start:        start,
+				End:          start + len(sym.text),
+				Continuation: i > 0})
+		}
+	}
+	return tokens
+}
+
+// encode repeatedly merges the lowest-ranked adjacent pair of symbols in word
+// until no more merges apply.
+func (t *bpeTokenizer) encode(word string) []symbol {
+	syms := []symbol{}
+	for i, r := range word {
+		syms = append(syms, symbol{text: string(r), start: i})
+	}
+
+	for len(syms) > 1 {
+		best, at := -1, -1
+		for i := 0; i < len(syms)-1; i++ {
+			rank, found := t.ranks[[2]string{syms[i].text, syms[i+1].text}]
+			if !found || (best >= 0 && rank >= best) {
+				continue
+			} else if t.vocab != nil && !t.vocab[syms[i].text+syms[i+1].text] {
+				continue
+			}
+			best, at = rank, i
+		}
+		if at < 0 {
+			break
+		}
+
+		// Merge every occurrence of the chosen pair, from left to right.
+		pair := [2]string{syms[at].text, syms[at+1].text}
+		merged := []symbol{}
+		for i := 0; i < len(syms); i++ {
+			if i < len(syms)-1 && syms[i].text == pair[0] && syms[i+1].text == pair[1] {
+				merged = append(merged, symbol{text: pair[0] + pair[1], start: syms[i].start})
+				i++
+			} else {
+				merged = append(merged, syms[i])
+			}
+		}
+		syms = merged
+	}
+
+	return syms
+}
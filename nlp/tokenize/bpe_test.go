@@ -0,0 +1,74 @@
+package tokenize_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jdkato/twine/nlp/tokenize"
+)
+
+var merges = `#version: 0.2
+l o
+lo w
+e r
+low er
+n e
+ne w
+e s
+es t`
+
+func TestBPETokenizer(t *testing.T) {
+	bpe, err := tokenize.NewBPETokenizer(strings.NewReader(merges), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := "lower newest, lowly"
+	expected := []tokenize.Token{
+		{Text: "lower", Start: 0, End: 5},
+		{Text: "new", Start: 6, End: 9},
+		{Text: "est", Start: 9, End: 12, Continuation: true},
+		{Text: ",", Start: 12, End: 13},
+		{Text: "low", Start: 14, End: 17},
+		{Text: "l", Start: 17, End: 18, Continuation: true},
+		{Text: "y", Start: 18, End: 19, Continuation: true},
+	}
+
+	observed := []tokenize.Token{}
+	for _, tok := range bpe.Tokenize(text) {
+		observed = append(observed, *tok)
+		if text[tok.Start:tok.End] != tok.Text {
+			t.Errorf("%q: offsets [%d, %d) point to %q", tok.Text, tok.Start, tok.End, text[tok.Start:tok.End])
+		}
+	}
+
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("got %v; expected %v", observed, expected)
+	}
+}
+
+func TestBPETokenizerVocab(t *testing.T) {
+	vocab := strings.NewReader("lo\nlow\nne\nnew\n")
+	bpe, err := tokenize.NewBPETokenizer(strings.NewReader(merges), vocab)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	observed := []string{}
+	for _, tok := range bpe.Tokenize("lower café") {
+		observed = append(observed, tok.Text)
+	}
+
+	expected := []string{"low", "e", "r", "c", "a", "f", "é"}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("got %q; expected %q", observed, expected)
+	}
+}
+
+func TestBPETokenizerInvalid(t *testing.T) {
+	_, err := tokenize.NewBPETokenizer(strings.NewReader("l o\nlow"), nil)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error for line 2, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package tokenize
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// A TokenizerConfig declaratively describes an iterTokenizer, as an
+// alternative to passing its functional options individually (e.g., when
+// loading settings from a JSON file).
+//
+// A nil slice keeps the default for its field, while an empty, non-nil one
+// disables it.
+type TokenizerConfig struct {
+	Prefixes     []string // See UsingPrefixes.
+	Suffixes     []string // See UsingSuffixes.
+	Contractions []string // See UsingContractions.
+	SplitCases   []string // See UsingSplitCases.
+	Emoticons    []string // See UsingEmoticons.
+	Units        []string // See WithUnits.
+
+	Special         string        // See UsingSpecialRE; "" keeps the default.
+	UnicodeForm     string        // "NFC", "NFD", "NFKC", "NFKD", or "" for none.
+	Possessive      string        // "split" (the default) or "keep".
+	Brackets        string        // "split" (the default) or "keep".
+	Timeout         time.Duration // See WithTokenizerTimeout.
+	SplitUnits      bool          // See WithSplitUnits.
+	NoSuffix        bool          // See WithoutSuffix.
+	Cashtags        bool          // See WithCashtags.
+	Mentions        bool          // See WithMentions.
+	StripInvisibles bool          // See WithStripInvisibles.
+}
+
+var unicodeForms = map[string]norm.Form{
+	"NFC": norm.NFC, "NFD": norm.NFD, "NFKC": norm.NFKC, "NFKD": norm.NFKD,
+}
+
+var possessivePolicies = map[string]PossessivePolicy{
+	"": SplitPossessive, "split": SplitPossessive, "keep": KeepPossessive,
+}
+
+var bracketPolicies = map[string]BracketPolicy{
+	"": SplitBrackets, "split": SplitBrackets, "keep": KeepBrackets,
+}
+
+// NewIterTokenizerFromConfig creates an iterTokenizer from the given config,
+// returning an error if any of its fields are invalid.
+func NewIterTokenizerFromConfig(cfg TokenizerConfig) (*iterTokenizer, error) {
+	opts := []TokenizerOptFunc{}
+
+	for _, field := range []struct {
+		name    string
+		entries []string
+	}{
+		{"prefixes", cfg.Prefixes}, {"suffixes", cfg.Suffixes},
+		{"contractions", cfg.Contractions}, {"split cases", cfg.SplitCases},
+		{"emoticons", cfg.Emoticons}, {"units", cfg.Units},
+	} {
+		for i, entry := range field.entries {
+			if entry == "" {
+				return nil, fmt.Errorf("%s: entry %d is empty", field.name, i)
+			}
+		}
+	}
+
+	if cfg.Prefixes != nil {
+		opts = append(opts, UsingPrefixes(cfg.Prefixes))
+	}
+	if cfg.Suffixes != nil {
+		opts = append(opts, UsingSuffixes(cfg.Suffixes))
+	}
+	if cfg.Contractions != nil {
+		opts = append(opts, UsingContractions(cfg.Contractions))
+	}
+	if cfg.SplitCases != nil {
+		opts = append(opts, UsingSplitCases(cfg.SplitCases))
+	}
+	if cfg.Emoticons != nil {
+		emoticons := map[string]int{}
+		for _, e := range cfg.Emoticons {
+			emoticons[e] = 1
+		}
+		opts = append(opts, UsingEmoticons(emoticons))
+	}
+	if cfg.Units != nil {
+		opts = append(opts, WithUnits(cfg.Units))
+	}
+
+	if cfg.Special != "" {
+		re, err := regexp.Compile(cfg.Special)
+		if err != nil {
+			return nil, fmt.Errorf("special: %w", err)
+		}
+		opts = append(opts, UsingSpecialRE(re))
+	}
+
+	if cfg.UnicodeForm != "" {
+		form, found := unicodeForms[cfg.UnicodeForm]
+		if !found {
+			return nil, fmt.Errorf("unicode form: unknown form %q", cfg.UnicodeForm)
+		}
+		opts = append(opts, WithNormalizeUnicodeForm(form))
+	}
+
+	policy, found := possessivePolicies[cfg.Possessive]
+	if !found {
+		return nil, fmt.Errorf("possessive: unknown policy %q", cfg.Possessive)
+	}
+	opts = append(opts, WithPossessivePolicy(policy))
+
+	brackets, found := bracketPolicies[cfg.Brackets]
+	if !found {
+		return nil, fmt.Errorf("brackets: unknown policy %q", cfg.Brackets)
+	}
+	opts = append(opts, WithBracketPolicy(brackets))
+
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("timeout: negative duration %v", cfg.Timeout)
+	} else if cfg.Timeout > 0 {
+		opts = append(opts, WithTokenizerTimeout(cfg.Timeout))
+	}
+	if cfg.SplitUnits {
+		opts = append(opts, WithSplitUnits())
+	}
+	if cfg.NoSuffix {
+		opts = append(opts, WithoutSuffix())
+	}
+	if cfg.Cashtags {
+		opts = append(opts, WithCashtags(true))
+	}
+	if cfg.Mentions {
+		opts = append(opts, WithMentions(true))
+	}
+	if cfg.StripInvisibles {
+		opts = append(opts, WithStripInvisibles(true))
+	}
+
+	return NewIterTokenizer(opts...), nil
+}
@@ -0,0 +1,95 @@
+package tokenize_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/jdkato/twine/nlp/tokenize"
+)
+
+func TestTokenizerConfig(t *testing.T) {
+	var cfg tokenize.TokenizerConfig
+	spec := `{"Units": ["mg"], "Possessive": "keep", "Suffixes": [".", ","]}`
+	if err := json.Unmarshal([]byte(spec), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	tokenizer, err := tokenize.NewIterTokenizerFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	observed := tokenizer.Tokenize("James's dose was 5mg (daily).")
+	expected := []string{"James's", "dose", "was", "5mg", "(", "daily)", "."}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("Tokenize: got %q; expected %q", observed, expected)
+	}
+
+	tokenizer, err = tokenize.NewIterTokenizerFromConfig(tokenize.TokenizerConfig{Brackets: "keep"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := "See (Figure 2) and [citation needed]."
+	observed = tokenizer.Tokenize(text)
+	keep := tokenize.NewIterTokenizer(tokenize.WithBracketPolicy(tokenize.KeepBrackets))
+	if expected = keep.Tokenize(text); !reflect.DeepEqual(observed, expected) {
+		t.Errorf("Tokenize(brackets): got %q; expected %q", observed, expected)
+	} else if reflect.DeepEqual(observed, tokenize.NewIterTokenizer().Tokenize(text)) {
+		t.Errorf("Tokenize(brackets): expected %q to differ from the default", observed)
+	}
+
+	tokenizer, err = tokenize.NewIterTokenizerFromConfig(tokenize.TokenizerConfig{
+		Prefixes: []string{"$", "@"}, Cashtags: true, Mentions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	observed = tokenizer.Tokenize("@desk bought $AAPL for $5.")
+	expected = []string{"@desk", "bought", "$AAPL", "for", "$", "5", "."}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("Tokenize(cashtags): got %q; expected %q", observed, expected)
+	}
+
+	tokenizer, err = tokenize.NewIterTokenizerFromConfig(tokenize.TokenizerConfig{StripInvisibles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text = "The docu\u00adment was\u200b clear."
+	observed = tokenizer.Tokenize(text)
+	expected = []string{"The", "document", "was", "clear", "."}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("Tokenize(invisibles): got %q; expected %q", observed, expected)
+	}
+	if tok := tokenizer.TokenizeWithOffsets(text)[1]; text[tok.Start:tok.End] != "docu\u00adment" {
+		t.Errorf("TokenizeWithOffsets(invisibles): got %q", text[tok.Start:tok.End])
+	}
+
+	tokenizer, err = tokenize.NewIterTokenizerFromConfig(tokenize.TokenizerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text = "He said, \"I can't go.\""
+	observed = tokenizer.Tokenize(text)
+	if expected = tokenize.NewIterTokenizer().Tokenize(text); !reflect.DeepEqual(observed, expected) {
+		t.Errorf("Tokenize(default): got %q; expected %q", observed, expected)
+	}
+}
+
+func TestTokenizerConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		cfg      tokenize.TokenizerConfig
+		expected string
+	}{
+		{tokenize.TokenizerConfig{Special: "("}, "special: error parsing regexp: missing closing ): `(`"},
+		{tokenize.TokenizerConfig{Prefixes: []string{"$", ""}}, "prefixes: entry 1 is empty"},
+		{tokenize.TokenizerConfig{UnicodeForm: "NFX"}, `unicode form: unknown form "NFX"`},
+		{tokenize.TokenizerConfig{Possessive: "drop"}, `possessive: unknown policy "drop"`},
+		{tokenize.TokenizerConfig{Brackets: "drop"}, `brackets: unknown policy "drop"`},
+		{tokenize.TokenizerConfig{Timeout: -1}, "timeout: negative duration -1ns"},
+	} {
+		if _, err := tokenize.NewIterTokenizerFromConfig(test.cfg); err == nil {
+			t.Errorf("%+v: expected an error", test.cfg)
+		} else if err.Error() != test.expected {
+			t.Errorf("%+v: got %q; expected %q", test.cfg, err, test.expected)
+		}
+	}
+}
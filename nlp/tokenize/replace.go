@@ -0,0 +1,47 @@
+package tokenize
+
+import "strings"
+
+// replacingTokenizer applies whole-token replacements (e.g., "u" -> "you")
+// to the output of another Tokenizer.
+type replacingTokenizer struct {
+	inner        Tokenizer
+	replacements map[string]string
+}
+
+// NewReplacingTokenizer creates a Tokenizer that replaces any token produced
+// by inner whose text exactly matches a key in replacements. To use it with
+// an iterTokenizer, wrap it with NewOffsetTokenizer.
+//
+// A replacement containing spaces (e.g., "gonna" -> "going to") produces one
+// token per word. Every token produced by a replacement keeps the offsets,
+// continuation flag, and annotations (each in its own copy of Meta) of the
+// token it replaced, so its Text may differ from text[Start:End] -- the
+// offsets always refer to the original input.
+func NewReplacingTokenizer(inner Tokenizer, replacements map[string]string) Tokenizer {
+	return &replacingTokenizer{inner: inner, replacements: replacements}
+}
+
+// Tokenize splits text into a slice of tokens, applying replacements.
+func (t *replacingTokenizer) Tokenize(text string) []*Token {
+	tokens := []*Token{}
+	for _, tok := range t.inner.Tokenize(text) {
+		replacement, found := t.replacements[tok.Text]
+		if !found {
+			tokens = append(tokens, tok)
+			continue
+		}
+		for _, word := range strings.Fields(replacement) {
+			replaced := &Token{
+				Text:         word,
+				Start:        tok.Start,
+				End:          tok.End,
+				Continuation: tok.Continuation}
+			for key, value := range tok.Meta {
+				replaced.Set(key, value)
+			}
+			tokens = append(tokens, replaced)
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,64 @@
+package tokenize_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jdkato/twine/nlp/tokenize"
+)
+
+// annotatingTokenizer marks each token produced by inner with its index.
+type annotatingTokenizer struct {
+	inner tokenize.Tokenizer
+}
+
+func (t annotatingTokenizer) Tokenize(text string) []*tokenize.Token {
+	tokens := t.inner.Tokenize(text)
+	for i, tok := range tokens {
+		tok.Set("index", i)
+	}
+	return tokens
+}
+
+func TestReplacingTokenizer(t *testing.T) {
+	replacements := map[string]string{
+		"u":     "you",
+		"gonna": "going to",
+		"go":    "go",
+	}
+	replacer := tokenize.NewReplacingTokenizer(
+		tokenize.NewOffsetTokenizer(tokenize.NewIterTokenizer()), replacements)
+
+	text := "u gonna go?"
+	expected := []tokenize.Token{
+		{Text: "you", Start: 0, End: 1},
+		{Text: "going", Start: 2, End: 7},
+		{Text: "to", Start: 2, End: 7},
+		{Text: "go", Start: 8, End: 10},
+		{Text: "?", Start: 10, End: 11},
+	}
+
+	observed := []tokenize.Token{}
+	for _, tok := range replacer.Tokenize(text) {
+		observed = append(observed, *tok)
+	}
+
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("got %v; expected %v", observed, expected)
+	}
+
+	annotated := tokenize.NewReplacingTokenizer(annotatingTokenizer{
+		tokenize.NewOffsetTokenizer(tokenize.NewIterTokenizer())}, replacements)
+
+	tokens := annotated.Tokenize(text)
+	for i, index := range []int{0, 1, 1, 2, 3} {
+		if value, _ := tokens[i].Get("index"); value != index {
+			t.Errorf("%q: got index %v; expected %d", tokens[i].Text, value, index)
+		}
+	}
+
+	tokens[1].Set("index", -1)
+	if value, _ := tokens[2].Get("index"); value != 1 {
+		t.Errorf("expected each replacement token to have its own Meta")
+	}
+}
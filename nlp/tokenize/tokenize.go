@@ -2,17 +2,41 @@ package tokenize
 
 import (
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/jdkato/twine/internal"
+	"golang.org/x/text/unicode/norm"
 )
 
 // A Token represents an individual token of text such as a word or punctuation
 // symbol.
 type Token struct {
-	Text string // The token's actual content.
+	Text         string // The token's actual content.
+	Start        int    // The byte offset of the token's first character.
+	End          int    // The byte offset just past the token's last character.
+	Continuation bool   // Whether the token continues the previous one's word.
+
+	// Meta holds arbitrary annotations (e.g., stop-word flags or custom
+	// scores); it's allocated on the first call to Set.
+	Meta map[string]any
+}
+
+// Set annotates the token with the given key and value.
+func (t *Token) Set(key string, value any) {
+	if t.Meta == nil {
+		t.Meta = map[string]any{}
+	}
+	t.Meta[key] = value
+}
+
+// Get returns the annotation stored under key, if any.
+func (t *Token) Get(key string) (any, bool) {
+	value, found := t.Meta[key]
+	return value, found
 }
 
 type TokenTester func(string) bool
@@ -25,6 +49,7 @@ type Tokenizer interface {
 type iterTokenizer struct {
 	specialRE      *regexp.Regexp
 	sanitizer      *strings.Replacer
+	sanitizerPairs []string
 	contractions   []string
 	splitCases     []string
 	suffixes       []string
@@ -32,10 +57,57 @@ type iterTokenizer struct {
 	emoticons      map[string]int
 	isUnsplittable TokenTester
 	noSuffix       bool
+	units          []string
+	unitRE         *regexp.Regexp
+	splitUnits     bool
+	normalize      bool
+	form           norm.Form
+	possessive     PossessivePolicy
+	brackets       BracketPolicy
+	cashtags       bool
+	mentions       bool
+	invisibles     bool
+	timeout        time.Duration
 }
 
 type TokenizerOptFunc func(*iterTokenizer)
 
+// A PossessivePolicy determines how possessives such as "James's" and
+// "dogs'" are tokenized.
+type PossessivePolicy int
+
+const (
+	// SplitPossessive splits the possessive clitic into its own token:
+	// "James's" -> [James, 's] and "dogs'" -> [dogs, '].
+	SplitPossessive PossessivePolicy = iota
+	// KeepPossessive keeps the possessive clitic attached: "James's",
+	// "James'", and "dogs'" are all single tokens.
+	//
+	// Since it's indistinguishable from a possessive, this also keeps the
+	// "'s" contraction (e.g., "He's") attached.
+	KeepPossessive
+)
+
+// A BracketPolicy determines whether brackets and quotes that enclose a word,
+// such as "(word)" or `"word"`, are tokenized separately.
+type BracketPolicy int
+
+const (
+	// SplitBrackets splits enclosing brackets and quotes into their own
+	// tokens: "(word)" -> [(, word, )].
+	SplitBrackets BracketPolicy = iota
+	// KeepBrackets keeps enclosing brackets and quotes attached: "(word)",
+	// "f(x)", and `"word"` are all single tokens. Punctuation outside of
+	// them, such as the comma in "(word),", is still split off.
+	KeepBrackets
+)
+
+// enclosers are the prefixes and suffixes kept attached by KeepBrackets.
+var enclosers = map[string]bool{
+	"(": true, ")": true, "[": true, "]": true, "{": true, "}": true,
+	`"`: true, "'": true,
+}
+
 // UsingIsUnsplittableFN gives a function that tests whether a token is splittable or not.
 func UsingIsUnsplittable(x TokenTester) TokenizerOptFunc {
 	return func(tokenizer *iterTokenizer) {
@@ -51,9 +123,13 @@ func UsingSpecialRE(x *regexp.Regexp) TokenizerOptFunc {
 }
 
 // Use the provided sanitizer.
+//
+// Since its replacements can't be inspected, TokenizeWithOffsets maps offsets
+// through it one character at a time (see TokenizeWithOffsets).
 func UsingSanitizer(x *strings.Replacer) TokenizerOptFunc {
 	return func(tokenizer *iterTokenizer) {
 		tokenizer.sanitizer = x
+		tokenizer.sanitizerPairs = nil
 	}
 }
 
@@ -98,6 +174,106 @@ func WithoutSuffix() TokenizerOptFunc {
 	}
 }
 
+// WithUnits keeps a number followed by one of the provided units (e.g.,
+// "5mg", "37°C", or "10km/h") together as a single quantity token.
+//
+// TokenizeWithOffsets annotates each quantity's token with the "kind"
+// "QUANTITY" (see Token.Get).
+func WithUnits(units []string) TokenizerOptFunc {
+	return func(tokenizer *iterTokenizer) {
+		tokenizer.units = units
+	}
+}
+
+// WithSplitUnits splits quantities recognized by WithUnits into two adjacent
+// tokens: the number and its unit (e.g., "5mg" -> [5, mg]).
+//
+// TokenizeWithOffsets annotates both tokens with the "kind" "QUANTITY" and
+// tells them apart with the "quantity" annotation, which is either "number"
+// or "unit".
+func WithSplitUnits() TokenizerOptFunc {
+	return func(tokenizer *iterTokenizer) {
+		tokenizer.splitUnits = true
+	}
+}
+
+// WithNormalizeUnicodeForm normalizes text to the given Unicode form (e.g.,
+// norm.NFC) before tokenizing it, so that a base letter followed by a
+// combining mark is treated the same as its precomposed equivalent.
+func WithNormalizeUnicodeForm(form norm.Form) TokenizerOptFunc {
+	return func(tokenizer *iterTokenizer) {
+		tokenizer.normalize = true
+		tokenizer.form = form
+	}
+}
+
+// WithPossessivePolicy sets how possessives are tokenized. The default is
+// SplitPossessive.
+func WithPossessivePolicy(x PossessivePolicy) TokenizerOptFunc {
+	return func(tokenizer *iterTokenizer) {
+		tokenizer.possessive = x
+	}
+}
+
+// WithBracketPolicy sets how enclosing brackets and quotes are tokenized. The
+// default is SplitBrackets.
+func WithBracketPolicy(x BracketPolicy) TokenizerOptFunc {
+	return func(tokenizer *iterTokenizer) {
+		tokenizer.brackets = x
+	}
+}
+
+// WithCashtags keeps stock-ticker cashtags, such as "$AAPL" or "$BRK.B",
+// together as single tokens rather than splitting off the "$" prefix.
+// Currency amounts (e.g., "$100") and a bare "$" are still split.
+//
+// TokenizeWithOffsets annotates each cashtag's token with the "kind"
+// "CASHTAG" (see Token.Get).
+func WithCashtags(x bool) TokenizerOptFunc {
+	return func(tokenizer *iterTokenizer) {
+		tokenizer.cashtags = x
+	}
+}
+
+// WithMentions keeps user mentions, such as "@user" or "@jane_doe", together
+// as single tokens even if "@" is one of the prefixes (by default, it isn't,
+// so mentions are already whole).
+//
+// TokenizeWithOffsets annotates each mention's token with the "kind"
+// "MENTION" (see Token.Get). A lone "@", or one within a word (e.g., an email
+// address), isn't a mention.
+func WithMentions(x bool) TokenizerOptFunc {
+	return func(tokenizer *iterTokenizer) {
+		tokenizer.mentions = x
+	}
+}
+
+// WithStripInvisibles removes invisible formatting characters -- soft
+// hyphens (U+00AD), zero-width spaces (U+200B), word joiners (U+2060), and
+// byte order marks (U+FEFF) -- before tokenizing, so that text extracted from
+// PDFs (e.g., "docu\u00ADment") yields clean tokens.
+//
+// Zero-width (non-)joiners are kept, since they're significant in emoji
+// sequences and some scripts.
+func WithStripInvisibles(x bool) TokenizerOptFunc {
+	return func(tokenizer *iterTokenizer) {
+		tokenizer.invisibles = x
+	}
+}
+
+// WithTokenizerTimeout bounds the time spent splitting any single
+// whitespace-delimited span of text. A span that takes longer is returned
+// as-is, as a single token, which TokenizeWithOffsets annotates with the
+// "kind" "FALLBACK" (see Token.Get).
+//
+// Regardless of this option, a span that would take quadratic time to split
+// (such as a long run of "(") falls back the same way.
+func WithTokenizerTimeout(x time.Duration) TokenizerOptFunc {
+	return func(tokenizer *iterTokenizer) {
+		tokenizer.timeout = x
+	}
+}
+
 // Constructor for default iterTokenizer
 func NewIterTokenizer(opts ...TokenizerOptFunc) *iterTokenizer {
 	tok := new(iterTokenizer)
@@ -108,6 +284,7 @@ func NewIterTokenizer(opts ...TokenizerOptFunc) *iterTokenizer {
 	tok.isUnsplittable = func(_ string) bool { return false }
 	tok.prefixes = prefixes
 	tok.sanitizer = sanitizer
+	tok.sanitizerPairs = sanitizerPairs
 	tok.specialRE = internalRE
 	tok.suffixes = suffixes
 	tok.noSuffix = false
@@ -118,89 +295,313 @@ func NewIterTokenizer(opts ...TokenizerOptFunc) *iterTokenizer {
 	}
 
 	tok.splitCases = append(tok.splitCases, tok.contractions...)
+	tok.unitRE = unitPattern(tok.units)
+
+	if tok.possessive == KeepPossessive {
+		cases := []string{}
+		for _, c := range tok.splitCases {
+			if c != "'s" {
+				cases = append(cases, c)
+			}
+		}
+		tok.splitCases = cases
+	}
+
+	if tok.brackets == KeepBrackets {
+		tok.prefixes = withoutBrackets(tok.prefixes)
+		tok.suffixes = withoutBrackets(tok.suffixes)
+	}
 
 	return tok
 }
 
-func addToken(s string, toks []string) []string {
-	if strings.TrimSpace(s) != "" {
-		toks = append(toks, s)
+// withoutBrackets returns the affixes that aren't brackets or quotes.
+func withoutBrackets(affixes []string) []string {
+	kept := []string{}
+	for _, affix := range affixes {
+		if !enclosers[affix] {
+			kept = append(kept, affix)
+		}
+	}
+	return kept
+}
+
+// unitPattern compiles a pattern matching a number immediately followed by
+// any of the given units, preferring the longest unit.
+func unitPattern(units []string) *regexp.Regexp {
+	if len(units) == 0 {
+		return nil
+	}
+
+	sorted := append([]string{}, units...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	for i, unit := range sorted {
+		sorted[i] = regexp.QuoteMeta(unit)
+	}
+
+	return regexp.MustCompile(
+		`^([+-]?\d+(?:[.,]\d+)*)(` + strings.Join(sorted, "|") + `)$`)
+}
+
+// A TokenTrace records how TokenizeTraced produced a token.
+type TokenTrace struct {
+	// Span is the whitespace-delimited text that the token was split from.
+	Span string
+	// Rule is the rule that emitted the token: "special" (an emoticon or a
+	// match of the special regex or unsplittable test), "quantity" (see
+	// WithUnits), "prefix", "split case" (the text before a contraction or
+	// other split case), "suffix", "word" (the text left once no other rule
+	// applies), or "fallback" (the span was too costly to split).
+	Rule string
+}
+
+// A tracer collects the rule behind each token added while tracing; a nil
+// *tracer only adds tokens.
+type tracer struct {
+	rules []string
+}
+
+// add appends s to toks, unless it's blank, recording rule as its source.
+func (tr *tracer) add(s, rule string, toks []string) []string {
+	if strings.TrimSpace(s) == "" {
+		return toks
+	}
+	if tr != nil {
+		tr.rules = append(tr.rules, rule)
+	}
+	return append(toks, s)
+}
+
+// mark returns the number of rules recorded so far, for use with reset.
+func (tr *tracer) mark() int {
+	if tr == nil {
+		return 0
+	}
+	return len(tr.rules)
+}
+
+// reset discards the rules recorded since mark.
+func (tr *tracer) reset(mark int) {
+	if tr != nil {
+		tr.rules = tr.rules[:mark]
 	}
-	return toks
 }
 
 func (t *iterTokenizer) isSpecial(token string) bool {
 	_, found := t.emoticons[token]
-	return found || t.specialRE.MatchString(token) || t.isUnsplittable(token)
+	return found || t.specialRE.MatchString(token) || t.isUnsplittable(token) ||
+		t.isPossessive(token)
+}
+
+// isPossessive determines if token is a plural possessive (e.g., "dogs'")
+// that should be kept whole. Tokens opening with a quote (e.g., "'yes'") are
+// treated as quotations instead, and those opening with a prefix (e.g.,
+// `"Charles'`) are only kept whole once it's been removed.
+func (t *iterTokenizer) isPossessive(token string) bool {
+	return t.possessive == KeepPossessive && len(token) > 2 &&
+		!strings.HasPrefix(token, "'") && !internal.HasAnyPrefix(token, t.prefixes) &&
+		strings.HasSuffix(strings.ToLower(token), "s'")
+}
+
+var cashtagRE = regexp.MustCompile(`^\$[A-Z]{1,6}(?:\.[A-Z]{1,2})?\b`)
+var mentionRE = regexp.MustCompile(`^@[\p{L}\p{N}_]+`)
+
+// isCashtag determines if token starts with a cashtag that WithCashtags
+// should keep together (e.g., "$AAPL" in "$AAPL's").
+func (t *iterTokenizer) isCashtag(token string) bool {
+	return t.cashtags && cashtagRE.MatchString(token)
+}
+
+// isMention determines if token starts with a mention that WithMentions
+// should keep together (e.g., "@user" in "@user's").
+func (t *iterTokenizer) isMention(token string) bool {
+	return t.mentions && mentionRE.MatchString(token)
+}
+
+// kind returns the annotation that TokenizeWithOffsets gives tok, a complete
+// token emitted by rule (see TokenTrace), or "" if there's none.
+func (t *iterTokenizer) kind(tok, rule string) string {
+	if rule == "quantity" {
+		return "QUANTITY"
+	} else if rule == "fallback" {
+		return "FALLBACK"
+	} else if loc := cashtagRE.FindStringIndex(tok); t.cashtags && loc != nil && loc[1] == len(tok) {
+		return "CASHTAG"
+	} else if loc := mentionRE.FindStringIndex(tok); t.mentions && loc != nil && loc[1] == len(tok) {
+		return "MENTION"
+	}
+	return ""
 }
 
-func (t *iterTokenizer) doSplit(token string) []string {
+// isNameApostrophe determines if the split case at token[idx] is actually an
+// apostrophe within a name, such as "O'Malley" or "D'Angelo" -- i.e., one
+// that's preceded by a letter and followed by a capitalized word.
+func isNameApostrophe(token string, idx int) bool {
+	if idx == 0 || token[idx] != '\'' {
+		return false
+	}
+	prev, _ := utf8.DecodeLastRuneInString(token[:idx])
+	next, size := utf8.DecodeRuneInString(token[idx+1:])
+	after, _ := utf8.DecodeRuneInString(token[idx+1+size:])
+	return unicode.IsLetter(prev) && unicode.IsUpper(next) && unicode.IsLower(after)
+}
+
+// splitCaseIndex returns the index of the first split case in token (whose
+// lowercased form is lower) that isn't a name apostrophe, or -1 if there's
+// none -- e.g., the possessive in "O'Sullivan's".
+func (t *iterTokenizer) splitCaseIndex(token, lower string) int {
+	for from := 0; from < len(lower); {
+		idx := internal.HasAnyIndex(lower[from:], t.splitCases)
+		if idx < 0 {
+			return -1
+		} else if idx += from; !isNameApostrophe(token, idx) {
+			return idx
+		}
+		from = idx + 1
+	}
+	return -1
+}
+
+// splitQuantity adds a quantity such as "5mg" to toks, either whole or as a
+// number and its unit. It reports whether token was a quantity.
+func (t *iterTokenizer) splitQuantity(token string, toks []string, tr *tracer) ([]string, bool) {
+	if t.unitRE == nil {
+		return toks, false
+	}
+
+	m := t.unitRE.FindStringSubmatch(token)
+	if m == nil {
+		return toks, false
+	} else if t.splitUnits {
+		return tr.add(m[2], "quantity", tr.add(m[1], "quantity", toks)), true
+	}
+
+	return tr.add(token, "quantity", toks), true
+}
+
+// Splitting a span of n bytes may scan up to maxSplitWork + splitWorkFactor*n
+// bytes. Each pass over a span re-scans what's left of it, so a long run of
+// punctuation (e.g., 20,000 "(") would otherwise take quadratic time; a
+// handful of passes over even a very long span is fine.
+const (
+	maxSplitWork    = 1 << 20
+	splitWorkFactor = 16
+)
+
+// A budget bounds the work done while splitting a single span.
+type budget struct {
+	work     int
+	deadline time.Time
+}
+
+func (t *iterTokenizer) newBudget(span string) *budget {
+	b := &budget{work: maxSplitWork + splitWorkFactor*len(span)}
+	if t.timeout > 0 {
+		b.deadline = time.Now().Add(t.timeout)
+	}
+	return b
+}
+
+// spend charges n bytes of work to the budget, reporting whether any of it
+// remains.
+func (b *budget) spend(n int) bool {
+	b.work -= n
+	if b.work < 0 {
+		return false
+	}
+	return b.deadline.IsZero() || time.Now().Before(b.deadline)
+}
+
+func (t *iterTokenizer) doSplit(token string, tr *tracer) []string {
 	tokens := []string{}
 	suffs := []string{}
 
+	span, b, mark := token, t.newBudget(token), tr.mark()
 	last := 0
 	for token != "" && utf8.RuneCountInString(token) != last {
-		if t.isSpecial(token) {
+		if !b.spend(len(token)) {
+			// Fall back to the unsplit span.
+			tr.reset(mark)
+			return tr.add(span, "fallback", []string{})
+		} else if t.isSpecial(token) {
 			// We've found a special case (e.g., an emoticon) -- so, we add it as a token without
 			// any further processing.
-			tokens = addToken(token, tokens)
+			tokens = tr.add(token, "special", tokens)
+			break
+		} else if toks, found := t.splitQuantity(token, tokens, tr); found {
+			tokens = toks
 			break
 		}
 		last = utf8.RuneCountInString(token)
 		lower := strings.ToLower(token)
-		if internal.HasAnyPrefix(token, t.prefixes) {
+		if internal.HasAnyPrefix(token, t.prefixes) && !t.isCashtag(token) && !t.isMention(token) {
 			// Remove prefixes -- e.g., $100 -> [$, 100].
-			tokens = addToken(string(token[0]), tokens)
+			tokens = tr.add(string(token[0]), "prefix", tokens)
 			token = token[1:]
-		} else if idx := internal.HasAnyIndex(lower, t.splitCases); idx > -1 {
+		} else if idx := t.splitCaseIndex(token, lower); idx > -1 {
 			// Handle "they'll", "I'll", "Don't", "won't", amount($).
 			//
 			// they'll -> [they, 'll].
 			// don't -> [do, n't].
 			// amount($) -> [amount, (, $, )].
-			tokens = addToken(token[:idx], tokens)
+			tokens = tr.add(token[:idx], "split case", tokens)
 			token = token[idx:]
 		} else if internal.HasAnySuffix(token, t.suffixes) {
 			// Remove suffixes -- e.g., Well) -> [Well, )].
 			suffs = append([]string{string(token[len(token)-1])}, suffs...)
 			token = token[:len(token)-1]
 		} else {
-			tokens = addToken(token, tokens)
+			tokens = tr.add(token, "word", tokens)
 		}
 	}
 
+	if tr != nil {
+		for range suffs {
+			tr.rules = append(tr.rules, "suffix")
+		}
+	}
 	return append(tokens, suffs...)
 }
 
-func (t *iterTokenizer) doSplitNoSuffix(token string) []string {
+func (t *iterTokenizer) doSplitNoSuffix(token string, tr *tracer) []string {
 	var tokens []string
 
+	span, b, mark := token, t.newBudget(token), tr.mark()
 	last := 0
 	for token != "" && utf8.RuneCountInString(token) != last {
-		if t.isSpecial(token) {
+		if !b.spend(len(token)) {
+			// Fall back to the unsplit span.
+			tr.reset(mark)
+			return tr.add(span, "fallback", nil)
+		} else if t.isSpecial(token) {
 			// We've found a special case (e.g., an emoticon) -- so, we add it as a token without
 			// any further processing.
-			tokens = addToken(token, tokens)
+			tokens = tr.add(token, "special", tokens)
+			break
+		} else if toks, found := t.splitQuantity(token, tokens, tr); found {
+			tokens = toks
 			break
 		}
 		last = utf8.RuneCountInString(token)
 		lower := strings.ToLower(token)
-		if internal.HasAnyPrefix(token, t.prefixes) {
+		if internal.HasAnyPrefix(token, t.prefixes) && !t.isCashtag(token) && !t.isMention(token) {
 			// Remove prefixes -- e.g., $100 -> [$, 100].
 			token = token[1:]
-		} else if idx := internal.HasAnyIndex(lower, t.splitCases); idx > -1 {
+		} else if idx := t.splitCaseIndex(token, lower); idx > -1 {
 			// Handle "they'll", "I'll", "Don't", "won't", amount($).
 			//
 			// they'll -> [they, 'll].
 			// don't -> [do, n't].
 			// amount($) -> [amount, (, $, )].
-			tokens = addToken(token[:idx], tokens)
+			tokens = tr.add(token[:idx], "split case", tokens)
 			token = token[idx:]
 		} else if internal.HasAnySuffix(token, t.suffixes) {
 			// Remove suffixes -- e.g., Well) -> [Well, )].
 			token = token[:len(token)-1]
 		} else {
-			tokens = addToken(token, tokens)
+			tokens = tr.add(token, "word", tokens)
 		}
 	}
 
@@ -209,9 +610,203 @@ func (t *iterTokenizer) doSplitNoSuffix(token string) []string {
 
 // tokenize splits a sentence into a slice of words.
 func (t *iterTokenizer) Tokenize(text string) []string {
+	return t.tokenize(text, nil)
+}
+
+// TokenizeTraced is like Tokenize, but also returns a TokenTrace for each
+// token recording the rule that produced it, which is useful for debugging
+// custom prefixes, suffixes, and split cases.
+func (t *iterTokenizer) TokenizeTraced(text string) ([]string, []TokenTrace) {
+	traces := []TokenTrace{}
+	return t.tokenize(text, &traces), traces
+}
+
+// TokenizeWithOffsets is like Tokenize, but returns each token along with
+// its byte offsets in text.
+//
+// Offsets account for the sanitizer and for the WithStripInvisibles and
+// WithNormalizeUnicodeForm options: each replacement (e.g., "&rsquo;" -> "'")
+// maps back to the string it replaced, and each normalized segment to the
+// combining sequence it came from. A sanitizer given by UsingSanitizer is
+// mapped one character at a time instead, so if it rewrites a longer string,
+// the tokens of the affected whitespace-delimited span all share the span's
+// offsets. A token's text is its tokenized (e.g., sanitized or normalized)
+// form, which may differ from text[Start:End].
+//
+// Quantities, cashtags, mentions, and spans that were too costly to split are
+// annotated as described by WithUnits, WithCashtags, WithMentions, and
+// WithTokenizerTimeout.
+func (t *iterTokenizer) TokenizeWithOffsets(text string) []*Token {
+	tokens := []*Token{}
+
+	start := -1
+	for i, r := range text + " " {
+		if !unicode.IsSpace(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		} else if start < 0 {
+			continue
+		}
+
+		raw := text[start:i]
+		clean := t.preprocess(raw)
+		// Tokenize always splits the final span with suffixes.
+		traces := []TokenTrace{}
+		toks := t.splitSpan(clean, t.noSuffix && i < len(text), &traces)
+
+		offsets := t.spanOffsets(raw, clean)
+		cursor, parts := 0, []string{"number", "unit"}
+		for j, tok := range toks {
+			tokStart, tokEnd := start, i
+			if idx := strings.Index(clean[cursor:], tok); idx >= 0 && offsets != nil {
+				cursor += idx
+				tokStart, tokEnd = start+offsets[cursor], start+offsets[cursor+len(tok)]
+				cursor += len(tok)
+			}
+			token := &Token{Text: tok, Start: tokStart, End: tokEnd}
+			if kind := t.kind(tok, traces[j].Rule); kind != "" {
+				token.Set("kind", kind)
+			}
+			if traces[j].Rule == "quantity" && t.splitUnits {
+				// A span holds at most one quantity: its number, then its unit.
+				token.Set("quantity", parts[0])
+				parts = parts[1:]
+			}
+			tokens = append(tokens, token)
+		}
+		start = -1
+	}
+
+	return tokens
+}
+
+// offsetTokenizer adapts an iterTokenizer to the Tokenizer interface.
+type offsetTokenizer struct {
+	iter *iterTokenizer
+}
+
+// NewOffsetTokenizer creates a Tokenizer from an iterTokenizer, whose tokens
+// carry their offsets (see TokenizeWithOffsets). This allows it to be used
+// wherever a Tokenizer is expected, such as with NewReplacingTokenizer.
+func NewOffsetTokenizer(t *iterTokenizer) Tokenizer {
+	return &offsetTokenizer{iter: t}
+}
+
+// Tokenize splits text into a slice of tokens.
+func (t *offsetTokenizer) Tokenize(text string) []*Token {
+	return t.iter.TokenizeWithOffsets(text)
+}
+
+// spanOffsets maps each byte offset in clean, the preprocessed form of raw,
+// to an offset in raw. It returns nil if clean can't be reproduced by
+// preprocessing raw piecewise.
+func (t *iterTokenizer) spanOffsets(raw, clean string) []int {
+	offsets := make([]int, len(raw)+1)
+	for i := range offsets {
+		offsets[i] = i
+	}
+	if raw == clean {
+		return offsets
+	}
+
+	text := raw
+	if t.invisibles {
+		text, offsets = mapPairs(text, offsets, invisiblePairs)
+	}
+	if t.normalize {
+		text, offsets = mapSegments(text, offsets, t.form)
+	}
+	if t.sanitizerPairs != nil {
+		text, offsets = mapPairs(text, offsets, t.sanitizerPairs)
+	} else {
+		text, offsets = mapRunes(text, offsets, t.sanitizer.Replace)
+	}
+	if text != clean {
+		return nil
+	}
+	return offsets
+}
+
+// mapRunes applies replace to text one rune at a time. It returns the result
+// along with the offset of each of its bytes in the original text, given
+// those of text's own bytes.
+func mapRunes(text string, offsets []int, replace func(string) string) (string, []int) {
+	var b strings.Builder
+	mapped := make([]int, 0, len(text)+1)
+	for i, r := range text {
+		piece := replace(string(r))
+		for j := 0; j < len(piece); j++ {
+			mapped = append(mapped, offsets[i])
+		}
+		b.WriteString(piece)
+	}
+	return b.String(), append(mapped, offsets[len(text)])
+}
+
+// mapPairs is like mapRunes, but replaces the old strings of the given
+// old-new pairs the way a strings.Replacer built from them would: left to
+// right, without overlapping, and preferring earlier pairs. A replacement's
+// bytes all map to the start of the string it replaced.
+func mapPairs(text string, offsets []int, pairs []string) (string, []int) {
+	var b strings.Builder
+
+	mapped := make([]int, 0, len(text)+1)
+	for i := 0; i < len(text); {
+		old, replacement := text[i:i+1], text[i:i+1]
+		for j := 0; j+1 < len(pairs); j += 2 {
+			if pairs[j] != "" && strings.HasPrefix(text[i:], pairs[j]) {
+				old, replacement = pairs[j], pairs[j+1]
+				break
+			}
+		}
+		for j := 0; j < len(replacement); j++ {
+			mapped = append(mapped, offsets[i])
+		}
+		b.WriteString(replacement)
+		i += len(old)
+	}
+	return b.String(), append(mapped, offsets[len(text)])
+}
+
+// mapSegments is like mapRunes, but normalizes text to form one segment at a
+// time (e.g., a base letter along with its combining marks), since a
+// segment's runes may compose into one.
+func mapSegments(text string, offsets []int, form norm.Form) (string, []int) {
+	var b strings.Builder
+	var it norm.Iter
+
+	mapped := make([]int, 0, len(text)+1)
+	it.InitString(form, text)
+	for !it.Done() {
+		start := it.Pos()
+		segment := it.Next()
+		for j := 0; j < len(segment); j++ {
+			mapped = append(mapped, offsets[start])
+		}
+		b.Write(segment)
+	}
+	return b.String(), append(mapped, offsets[len(text)])
+}
+
+// preprocess applies the tokenizer's text transformations (e.g., its
+// sanitizer) ahead of splitting.
+func (t *iterTokenizer) preprocess(text string) string {
+	if t.invisibles {
+		text = invisibles.Replace(text)
+	}
+	if t.normalize {
+		text = t.form.String(text)
+	}
+	return t.sanitizer.Replace(text)
+}
+
+// tokenize implements Tokenize, appending to traces if it isn't nil.
+func (t *iterTokenizer) tokenize(text string, traces *[]TokenTrace) []string {
 	var tokens []string
 
-	clean, white := t.sanitizer.Replace(text), false
+	clean, white := t.preprocess(text), false
 	length := len(clean)
 
 	start, index := 0, 0
@@ -226,15 +821,10 @@ func (t *iterTokenizer) Tokenize(text string) []string {
 		if unicode.IsSpace(uc) != white {
 			if start < index {
 				span := clean[start:index]
-				if toks, found := cache[span]; found {
+				if toks, found := cache[span]; found && traces == nil {
 					tokens = append(tokens, toks...)
 				} else {
-					toks := []string{}
-					if t.noSuffix {
-						toks = t.doSplitNoSuffix(span)
-					} else {
-						toks = t.doSplit(span)
-					}
+					toks := t.splitSpan(span, t.noSuffix, traces)
 					cache[span] = toks
 					tokens = append(tokens, toks...)
 				}
@@ -250,19 +840,49 @@ func (t *iterTokenizer) Tokenize(text string) []string {
 	}
 
 	if start < index {
-		tokens = append(tokens, t.doSplit(clean[start:index])...)
+		tokens = append(tokens, t.splitSpan(clean[start:index], false, traces)...)
 	}
 
 	return tokens
 }
 
+// splitSpan splits a single whitespace-delimited span, appending a
+// TokenTrace for each of its tokens to traces if it isn't nil.
+func (t *iterTokenizer) splitSpan(span string, noSuffix bool, traces *[]TokenTrace) []string {
+	var tr *tracer
+	if traces != nil {
+		tr = &tracer{}
+	}
+
+	var toks []string
+	if noSuffix {
+		toks = t.doSplitNoSuffix(span, tr)
+	} else {
+		toks = t.doSplit(span, tr)
+	}
+
+	if tr != nil {
+		for _, rule := range tr.rules {
+			*traces = append(*traces, TokenTrace{Span: span, Rule: rule})
+		}
+	}
+	return toks
+}
+
 var internalRE = regexp.MustCompile(`^(?:[A-Za-z]\.){2,}$|^[A-Z][a-z]{1,2}\.$`)
-var sanitizer = strings.NewReplacer(
+var sanitizerPairs = []string{
 	"\u201c", `"`,
 	"\u201d", `"`,
 	"\u2018", "'",
 	"\u2019", "'",
-	"&rsquo;", "'")
+	"&rsquo;", "'"}
+var sanitizer = strings.NewReplacer(sanitizerPairs...)
+var invisiblePairs = []string{
+	"\u00ad", "",
+	"\u200b", "",
+	"\u2060", "",
+	"\ufeff", ""}
+var invisibles = strings.NewReplacer(invisiblePairs...)
 var contractions = []string{"'ll", "'s", "'re", "'m", "n't"}
 var suffixes = []string{",", ")", `"`, "]", "!", ";", ".", "?", ":", "'"}
 var prefixes = []string{"$", "(", `"`, "["}
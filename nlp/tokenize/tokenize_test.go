@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jdkato/twine/internal"
 	"github.com/jdkato/twine/nlp/tokenize"
+	"golang.org/x/text/unicode/norm"
 )
 
 var testdata = "../../testdata"
@@ -204,6 +207,375 @@ func TestTokenizationContractions(t *testing.T) {
 	checkTokens(t, tokens, expected, "TokenizationContraction(custom-missing)")
 }
 
+func TestTokenizationUnits(t *testing.T) {
+	units := []string{"mg", "°C", "°F", "km/h", "m/s²", "m"}
+
+	tokenizer := tokenize.NewIterTokenizer(tokenize.WithUnits(units))
+	tokens := tokenizer.Tokenize("Take 5mg at 37°C (or 98.6°F), then drive 10km/h.")
+	expected := []string{
+		"Take", "5mg", "at", "37°C", "(", "or", "98.6°F", ")", ",", "then",
+		"drive", "10km/h", "."}
+	checkTokens(t, tokens, expected, "TokenizationUnits(glued)")
+
+	tokenizer = tokenize.NewIterTokenizer(
+		tokenize.WithUnits(units), tokenize.WithSplitUnits())
+	tokens = tokenizer.Tokenize("Take 5mg at 37°C (or 98.6°F), then accelerate at 9.8m/s².")
+	expected = []string{
+		"Take", "5", "mg", "at", "37", "°C", "(", "or", "98.6", "°F", ")", ",",
+		"then", "accelerate", "at", "9.8", "m/s²", "."}
+	checkTokens(t, tokens, expected, "TokenizationUnits(split)")
+
+	tokens = tokenizer.Tokenize("The 5mgs and mg5 aren't units.")
+	expected = []string{"The", "5mgs", "and", "mg5", "are", "n't", "units", "."}
+	checkTokens(t, tokens, expected, "TokenizationUnits(unknown)")
+}
+
+func TestTokenizationUnitsWithOffsets(t *testing.T) {
+	units := []string{"°C", "km/h"}
+	text := "At 37°C, drive (10km/h)."
+
+	glued := tokenize.NewIterTokenizer(tokenize.WithUnits(units))
+	split := tokenize.NewIterTokenizer(tokenize.WithUnits(units), tokenize.WithSplitUnits())
+	for name, tc := range map[string]struct {
+		tokenize func(string) []*tokenize.Token
+		parts    map[string]string // the quantity tokens and their parts
+	}{
+		"glued": {glued.TokenizeWithOffsets, map[string]string{"37°C": "", "10km/h": ""}},
+		"split": {split.TokenizeWithOffsets, map[string]string{"37": "number", "°C": "unit", "10": "number", "km/h": "unit"}},
+	} {
+		found := 0
+		for _, tok := range tc.tokenize(text) {
+			if text[tok.Start:tok.End] != tok.Text {
+				t.Errorf("%s: %q at [%d:%d] is %q", name, tok.Text, tok.Start, tok.End, text[tok.Start:tok.End])
+			}
+
+			kind, _ := tok.Get("kind")
+			part, hasPart := tok.Get("quantity")
+			if expected, isQuantity := tc.parts[tok.Text]; !isQuantity {
+				if kind != nil || hasPart {
+					t.Errorf("%s: %q: unexpected kind %v (%v)", name, tok.Text, kind, part)
+				}
+			} else if found++; kind != "QUANTITY" {
+				t.Errorf("%s: %q: got kind %v; expected QUANTITY", name, tok.Text, kind)
+			} else if expected == "" && hasPart {
+				t.Errorf("%s: %q: unexpected part %v", name, tok.Text, part)
+			} else if expected != "" && part != expected {
+				t.Errorf("%s: %q: got part %v; expected %q", name, tok.Text, part, expected)
+			}
+		}
+		if found != len(tc.parts) {
+			t.Errorf("%s: found %d quantity tokens; expected %d", name, found, len(tc.parts))
+		}
+	}
+}
+
+func TestTokenizationUnicodeForm(t *testing.T) {
+	composed := "Le caf\u00e9 (d\u00e9j\u00e0 vu) \u00e9tait ferm\u00e9."
+	decomposed := "Le cafe\u0301 (de\u0301ja\u0300 vu) e\u0301tait ferme\u0301."
+	expected := []string{
+		"Le", "caf\u00e9", "(", "d\u00e9j\u00e0", "vu", ")", "\u00e9tait",
+		"ferm\u00e9", "."}
+
+	tokenizer := tokenize.NewIterTokenizer(tokenize.WithNormalizeUnicodeForm(norm.NFC))
+	checkTokens(t, tokenizer.Tokenize(composed), expected, "TokenizationUnicodeForm(composed)")
+	checkTokens(t, tokenizer.Tokenize(decomposed), expected, "TokenizationUnicodeForm(decomposed)")
+
+	tokens := tokenize.NewIterTokenizer().Tokenize(decomposed)
+	if reflect.DeepEqual(tokens, expected) {
+		t.Errorf("TokenizationUnicodeForm(default): expected input to be left as-is")
+	}
+
+	sources := []string{
+		"Le", "cafe\u0301", "(", "de\u0301ja\u0300", "vu", ")", "e\u0301tait",
+		"ferme\u0301", "."}
+	for i, tok := range tokenizer.TokenizeWithOffsets(decomposed) {
+		if tok.Text != expected[i] {
+			t.Errorf("TokenizationUnicodeForm(offsets): got %q; expected %q", tok.Text, expected[i])
+		} else if source := decomposed[tok.Start:tok.End]; source != sources[i] {
+			t.Errorf("TokenizationUnicodeForm(offsets): %q at [%d:%d] is %q; expected %q",
+				tok.Text, tok.Start, tok.End, source, sources[i])
+		}
+	}
+}
+
+func TestTokenizationPossessives(t *testing.T) {
+	text := "James's car, James' house, and (the dogs') toys."
+
+	tokens := tokenize.NewIterTokenizer().Tokenize(text)
+	expected := []string{
+		"James", "'s", "car", ",", "James", "'", "house", ",", "and", "(",
+		"the", "dogs", "'", ")", "toys", "."}
+	checkTokens(t, tokens, expected, "TokenizationPossessives(split)")
+
+	tokens = tokenize.NewIterTokenizer(
+		tokenize.WithPossessivePolicy(tokenize.SplitPossessive)).Tokenize(text)
+	checkTokens(t, tokens, expected, "TokenizationPossessives(split-explicit)")
+
+	keep := tokenize.NewIterTokenizer(
+		tokenize.WithPossessivePolicy(tokenize.KeepPossessive))
+	tokens = keep.Tokenize(text)
+	expected = []string{
+		"James's", "car", ",", "James'", "house", ",", "and", "(", "the",
+		"dogs'", ")", "toys", "."}
+	checkTokens(t, tokens, expected, "TokenizationPossessives(keep)")
+
+	tokens = keep.Tokenize("They don't say 'yes'.")
+	expected = []string{"They", "do", "n't", "say", "'yes", "'", "."}
+	checkTokens(t, tokens, expected, "TokenizationPossessives(keep-contractions)")
+
+	tokens = keep.Tokenize(`He said "Charles' book" was like [Jesus' words] or "the dogs'".`)
+	expected = []string{
+		"He", "said", `"`, "Charles'", "book", `"`, "was", "like", "[", "Jesus'",
+		"words", "]", "or", `"`, "the", "dogs'", `"`, "."}
+	checkTokens(t, tokens, expected, "TokenizationPossessives(keep-prefixes)")
+}
+
+func TestTokenizationPathological(t *testing.T) {
+	// Each pass over a span re-scans it, so this used to take minutes.
+	span := strings.Repeat("(", 50000) + "a"
+
+	tokens := tokenize.NewIterTokenizer().Tokenize("See " + span + " here")
+	expected := []string{"See", span, "here"}
+	checkTokens(t, tokens, expected, "TokenizationPathological(budget)")
+
+	span = strings.Repeat("(", 1000) + "a"
+	if n := len(tokenize.NewIterTokenizer().Tokenize(span)); n != 1001 {
+		t.Errorf("TokenizationPathological(under-budget): got %d tokens", n)
+	}
+
+	// A long span that only needs a few passes is split as usual.
+	long := strings.Repeat("a,", 600000) + "end"
+	checkTokens(t, tokenize.NewIterTokenizer().Tokenize(long+"."), []string{long, "."},
+		"TokenizationPathological(long)")
+
+	tokenizer := tokenize.NewIterTokenizer(tokenize.WithTokenizerTimeout(time.Millisecond))
+	checkTokens(t, tokenizer.Tokenize(span), []string{span}, "TokenizationPathological(timeout)")
+	checkTokens(t, tokenizer.Tokenize("(a)"), []string{"(", "a", ")"}, "TokenizationPathological(timeout-normal)")
+
+	text := "See " + strings.Repeat("(", 50000) + "a (here)."
+	toks := tokenize.NewIterTokenizer().TokenizeWithOffsets(text)
+	if len(toks) != 6 {
+		t.Fatalf("TokenizationPathological(offsets): got %d tokens; expected 6", len(toks))
+	}
+	for i, tok := range toks {
+		kind, _ := tok.Get("kind")
+		if text[tok.Start:tok.End] != tok.Text {
+			t.Errorf("TokenizationPathological(offsets): %q at [%d:%d]", tok.Text, tok.Start, tok.End)
+		} else if i == 1 && kind != "FALLBACK" {
+			t.Errorf("TokenizationPathological(offsets): got kind %v; expected FALLBACK", kind)
+		} else if i != 1 && kind != nil {
+			t.Errorf("TokenizationPathological(offsets): %q: unexpected kind %v", tok.Text, kind)
+		}
+	}
+}
+
+func TestTokenMeta(t *testing.T) {
+	tok := tokenize.Token{Text: "the"}
+	if _, found := tok.Get("stop"); found || tok.Meta != nil {
+		t.Error("TokenMeta: expected no annotations")
+	}
+
+	tok.Set("stop", true)
+	tok.Set("score", 0.5)
+	if value, found := tok.Get("stop"); !found || value != true {
+		t.Errorf("TokenMeta: got (%v, %v)", value, found)
+	}
+
+	data, err := json.Marshal(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded tokenize.Token
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tok, decoded) {
+		t.Errorf("TokenMeta: got %v after a JSON round trip; expected %v", decoded, tok)
+	}
+}
+
+func TestTokenizationNames(t *testing.T) {
+	tokenizer := tokenize.NewIterTokenizer()
+
+	tokens := tokenizer.Tokenize("O'Malley, O'Reilly, and D'Angelo met O'Brien's friend.")
+	expected := []string{
+		"O'Malley", ",", "O'Reilly", ",", "and", "D'Angelo", "met", "O'Brien",
+		"'s", "friend", "."}
+	checkTokens(t, tokens, expected, "TokenizationNames(surnames)")
+
+	tokens = tokenizer.Tokenize("O'Sullivan's dog, O'Shea's cat, and D'Souza's car.")
+	expected = []string{
+		"O'Sullivan", "'s", "dog", ",", "O'Shea", "'s", "cat", ",", "and",
+		"D'Souza", "'s", "car", "."}
+	checkTokens(t, tokens, expected, "TokenizationNames(possessives)")
+
+	tokens = tokenizer.Tokenize("I'm sure they'll say I'M fine, but WE'LL see.")
+	expected = []string{
+		"I", "'m", "sure", "they", "'ll", "say", "I", "'M", "fine", ",", "but",
+		"WE", "'LL", "see", "."}
+	checkTokens(t, tokens, expected, "TokenizationNames(contractions)")
+}
+
+func TestTokenizationAlphanumeric(t *testing.T) {
+	tokenizer := tokenize.NewIterTokenizer()
+
+	tokens := tokenizer.Tokenize("Update to v1.2.3. Then set 0xFF, A1B2-C3, and (0x1F) on v2.0-rc.1!")
+	expected := []string{
+		"Update", "to", "v1.2.3", ".", "Then", "set", "0xFF", ",", "A1B2-C3", ",",
+		"and", "(", "0x1F", ")", "on", "v2.0-rc.1", "!"}
+	checkTokens(t, tokens, expected, "TokenizationAlphanumeric(codes)")
+
+	tokens = tokenizer.Tokenize("Version 3.14.15 of iOS13 shipped in 2019.")
+	expected = []string{"Version", "3.14.15", "of", "iOS13", "shipped", "in", "2019", "."}
+	checkTokens(t, tokens, expected, "TokenizationAlphanumeric(prose)")
+}
+
+func TestTokenizeTraced(t *testing.T) {
+	tokenizer := tokenize.NewIterTokenizer(tokenize.WithUnits([]string{"mg"}))
+
+	text := `"Hi," I can't take (5mg) :-)`
+	tokens, traces := tokenizer.TokenizeTraced(text)
+	checkTokens(t, tokens, tokenizer.Tokenize(text), "TokenizeTraced")
+
+	expected := []tokenize.TokenTrace{
+		{Span: `"Hi,"`, Rule: "prefix"},
+		{Span: `"Hi,"`, Rule: "word"},
+		{Span: `"Hi,"`, Rule: "suffix"},
+		{Span: `"Hi,"`, Rule: "suffix"},
+		{Span: "I", Rule: "word"},
+		{Span: "can't", Rule: "split case"},
+		{Span: "can't", Rule: "word"},
+		{Span: "take", Rule: "word"},
+		{Span: "(5mg)", Rule: "prefix"},
+		{Span: "(5mg)", Rule: "quantity"},
+		{Span: "(5mg)", Rule: "suffix"},
+		{Span: ":-)", Rule: "special"},
+	}
+	if !reflect.DeepEqual(traces, expected) {
+		t.Errorf("TokenizeTraced: got %+v; expected %+v", traces, expected)
+	}
+
+	tokens, traces = tokenizer.TokenizeTraced(strings.Repeat("(", 2000))
+	if len(tokens) != 1 || len(traces) != 1 || traces[0].Rule != "fallback" {
+		t.Errorf("TokenizeTraced(fallback): got %d tokens and %+v", len(tokens), traces)
+	}
+}
+
+func TestTokenizationBrackets(t *testing.T) {
+	text := `Call f(x) ((twice)), then "quote [1]" and "stop here.`
+
+	tokens := tokenize.NewIterTokenizer().Tokenize(text)
+	expected := []string{
+		"Call", "f(x", ")", "(", "(", "twice", ")", ")", ",", "then", `"`, "quote",
+		"[", "1", "]", `"`, "and", `"`, "stop", "here", "."}
+	checkTokens(t, tokens, expected, "TokenizationBrackets(split)")
+
+	tokenizer := tokenize.NewIterTokenizer(tokenize.WithBracketPolicy(tokenize.KeepBrackets))
+	tokens = tokenizer.Tokenize(text)
+	expected = []string{
+		"Call", "f(x)", "((twice))", ",", "then", `"quote`, "[1]\"", "and", `"stop`,
+		"here", "."}
+	checkTokens(t, tokens, expected, "TokenizationBrackets(keep)")
+}
+
+func TestTokenizationCashtags(t *testing.T) {
+	text := "Bought $AAPL and ($BRK.B) for $100, not $aapl; @trader's call on $TSLA."
+
+	tokens := tokenize.NewIterTokenizer().Tokenize(text)
+	expected := []string{
+		"Bought", "$", "AAPL", "and", "(", "$", "BRK.B", ")", "for", "$", "100", ",",
+		"not", "$", "aapl", ";", "@trader", "'s", "call", "on", "$", "TSLA", "."}
+	checkTokens(t, tokens, expected, "TokenizationCashtags(default)")
+
+	tokens = tokenize.NewIterTokenizer(tokenize.WithCashtags(true)).Tokenize(text)
+	expected = []string{
+		"Bought", "$AAPL", "and", "(", "$BRK.B", ")", "for", "$", "100", ",", "not",
+		"$", "aapl", ";", "@trader", "'s", "call", "on", "$TSLA", "."}
+	checkTokens(t, tokens, expected, "TokenizationCashtags")
+
+	tokenizer := tokenize.NewIterTokenizer(
+		tokenize.WithCashtags(true), tokenize.WithMentions(true),
+		tokenize.UsingPrefixes([]string{"$", "(", "@"}))
+	text = "@trader: $AAPL, not $5 or $ (@desk) @ a@b.com"
+	tokens = tokenizer.Tokenize(text)
+	expected = []string{
+		"@trader", ":", "$AAPL", ",", "not", "$", "5", "or", "$", "(", "@desk",
+		")", "@", "a@b.com"}
+	checkTokens(t, tokens, expected, "TokenizationCashtags(mentions)")
+
+	kinds := map[string]string{"@trader": "MENTION", "$AAPL": "CASHTAG", "@desk": "MENTION"}
+	for _, tok := range tokenizer.TokenizeWithOffsets(text) {
+		if text[tok.Start:tok.End] != tok.Text {
+			t.Errorf("%q: got offsets [%d, %d)", tok.Text, tok.Start, tok.End)
+		}
+		if kind, _ := tok.Get("kind"); kind != nil && kind != kinds[tok.Text] {
+			t.Errorf("%q: got kind %v; expected %q", tok.Text, kind, kinds[tok.Text])
+		} else if kind == nil && kinds[tok.Text] != "" {
+			t.Errorf("%q: expected kind %q", tok.Text, kinds[tok.Text])
+		}
+	}
+
+	for _, tok := range tokenize.NewIterTokenizer().TokenizeWithOffsets(text) {
+		if _, found := tok.Get("kind"); found {
+			t.Errorf("%q: unexpected kind by default", tok.Text)
+		}
+	}
+}
+
+func TestTokenizationInvisibles(t *testing.T) {
+	text := "\ufeffThe docu\u00adment's con\u00adclu\u00adsion was\u200b clear."
+
+	tokens := tokenize.NewIterTokenizer().Tokenize(text)
+	expected := []string{
+		"\ufeffThe", "docu\u00adment", "'s", "con\u00adclu\u00adsion", "was\u200b",
+		"clear", "."}
+	checkTokens(t, tokens, expected, "TokenizationInvisibles(default)")
+
+	tokens = tokenize.NewIterTokenizer(tokenize.WithStripInvisibles(true)).Tokenize(text)
+	expected = []string{"The", "document", "'s", "conclusion", "was", "clear", "."}
+	checkTokens(t, tokens, expected, "TokenizationInvisibles")
+}
+
+func TestTokenizeWithOffsets(t *testing.T) {
+	tokenizer := tokenize.NewIterTokenizer(tokenize.WithStripInvisibles(true))
+
+	text := "“Hello,” said docu\u00adment—owner  J.R.\n(5 words)."
+	tokens := tokenizer.TokenizeWithOffsets(text)
+
+	texts := []string{}
+	for _, tok := range tokens {
+		texts = append(texts, tok.Text)
+	}
+	checkTokens(t, texts, tokenizer.Tokenize(text), "TokenizeWithOffsets")
+
+	expected := []string{
+		"“", "Hello", ",", "”", "said", "docu\u00adment—owner", "J.R.", "(", "5",
+		"words", ")", "."}
+	if len(tokens) != len(expected) {
+		t.Fatalf("TokenizeWithOffsets: got %d tokens; expected %d", len(tokens), len(expected))
+	}
+	for i, tok := range tokens {
+		if source := text[tok.Start:tok.End]; source != expected[i] {
+			t.Errorf("TokenizeWithOffsets: %q at [%d:%d] is %q; expected %q",
+				tok.Text, tok.Start, tok.End, source, expected[i])
+		}
+	}
+
+	text = "I don&rsquo;t know what he\u00ad&rsquo;s done."
+	tokens = tokenizer.TokenizeWithOffsets(text)
+	expected = []string{"I", "do", "n&rsquo;t", "know", "what", "he\u00ad", "&rsquo;s", "done", "."}
+	if len(tokens) != len(expected) {
+		t.Fatalf("TokenizeWithOffsets(entities): got %d tokens; expected %d", len(tokens), len(expected))
+	}
+	for i, tok := range tokens {
+		if source := text[tok.Start:tok.End]; source != expected[i] {
+			t.Errorf("TokenizeWithOffsets(entities): %q at [%d:%d] is %q; expected %q",
+				tok.Text, tok.Start, tok.End, source, expected[i])
+		}
+	}
+}
+
 func BenchmarkTokenization(b *testing.B) {
 	in := internal.ReadDataFile(filepath.Join(testdata, "sherlock.txt"))
 	text := string(in)
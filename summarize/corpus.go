@@ -0,0 +1,68 @@
+package summarize
+
+import (
+	"math"
+	"strings"
+)
+
+// A Corpus tracks document frequencies across a collection of Documents.
+//
+// Like Keywords, it considers each Document's words after normalizing case
+// and omitting stop words.
+type Corpus struct {
+	NumDocuments int            // Number of Documents added
+	Frequency    map[string]int // [term]number of Documents containing it
+}
+
+// NewCorpus creates an empty Corpus.
+func NewCorpus() *Corpus {
+	return &Corpus{Frequency: map[string]int{}}
+}
+
+// Add counts the keywords of the Document d.
+func (c *Corpus) Add(d *Document) {
+	c.NumDocuments++
+	for word := range d.Keywords() {
+		c.Frequency[word]++
+	}
+}
+
+// FrequencySorted returns the Corpus' Frequency ordered by document
+// frequency (highest first) and then alphabetically.
+func (c *Corpus) FrequencySorted() []WordCount {
+	return sortedCounts(c.Frequency)
+}
+
+// DocumentFrequency returns the number of Documents that contain term.
+func (c *Corpus) DocumentFrequency(term string) int {
+	return c.Frequency[strings.ToLower(term)]
+}
+
+// IDF returns the smoothed inverse document frequency of term,
+//
+//	ln((1 + N) / (1 + df)) + 1
+//
+// where N is the number of Documents and df is term's document frequency.
+// It's defined for terms that aren't in any Document, which have the largest
+// IDF.
+func (c *Corpus) IDF(term string) float64 {
+	n, df := float64(c.NumDocuments), float64(c.DocumentFrequency(term))
+	return math.Log((1+n)/(1+df)) + 1
+}
+
+// TFIDF returns the TF-IDF score of each of the Document d's keywords, where
+// a keyword's term frequency is its count divided by the number of words in
+// d.
+func (c *Corpus) TFIDF(d *Document) map[string]float64 {
+	scores := map[string]float64{}
+	for word, count := range d.Keywords() {
+		scores[word] = float64(count) / d.NumWords * c.IDF(word)
+	}
+	return scores
+}
+
+// TFIDFSorted returns the result of TFIDF ordered by score (highest first)
+// and then alphabetically.
+func (c *Corpus) TFIDFSorted(d *Document) []WordScore {
+	return sortedScores(c.TFIDF(d))
+}
@@ -0,0 +1,49 @@
+package summarize
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCorpus(t *testing.T) {
+	c := NewCorpus()
+	c.Add(NewDocument("The cat sat on the mat."))
+	c.Add(NewDocument("A cat and a dog."))
+	c.Add(NewDocument("The dog barked at the Cat."))
+
+	for term, expected := range map[string]int{
+		"cat": 3, "Cat": 3, "dog": 2, "mat": 1, "the": 0, "bird": 0} {
+		if observed := c.DocumentFrequency(term); observed != expected {
+			t.Errorf("DocumentFrequency(%q): got %d; expected %d", term, observed, expected)
+		}
+	}
+
+	freqs := c.FrequencySorted()
+	if len(freqs) != len(c.Frequency) || freqs[0] != (WordCount{"cat", 3}) || freqs[1] != (WordCount{"dog", 2}) {
+		t.Errorf("FrequencySorted: got %v", freqs)
+	}
+	for i := 2; i < len(freqs); i++ {
+		if freqs[i].Count != 1 || (i > 2 && freqs[i-1].Word > freqs[i].Word) {
+			t.Errorf("FrequencySorted: %v out of order", freqs[i])
+		}
+	}
+
+	if idf := c.IDF("cat"); idf != 1 {
+		t.Errorf("IDF(cat): got %v; expected 1", idf)
+	}
+	if idf, expected := c.IDF("mat"), math.Log(2)+1; idf != expected {
+		t.Errorf("IDF(mat): got %v; expected %v", idf, expected)
+	}
+	if c.IDF("bird") <= c.IDF("mat") {
+		t.Errorf("IDF(bird): expected more than IDF(mat)")
+	}
+
+	d := NewDocument("The dog chased the dog's cat.")
+	scores := c.TFIDF(d)
+	if scores["cat"] >= scores["chased"] {
+		t.Errorf("TFIDF: expected cat (%v) < chased (%v)", scores["cat"], scores["chased"])
+	}
+	if _, found := scores["the"]; found {
+		t.Errorf("TFIDF: expected no score for stop words")
+	}
+}
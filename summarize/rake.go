@@ -0,0 +1,95 @@
+package summarize
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A Keyword is a key phrase found by RAKE (see KeyPhrases).
+type Keyword struct {
+	Phrase   string    // the lowercased phrase
+	Score    float64   // the sum of its words' degree-to-frequency ratios
+	Mentions []Mention // each occurrence, in Document order
+}
+
+// A Mention locates a phrase within a Document.
+type Mention struct {
+	Sentence int // the index of the sentence in the Document's Sentences
+	Start    int // the byte offset of its first character in the sentence's Text
+	End      int // the byte offset just past its last character
+}
+
+var reRakeWord = regexp.MustCompile(`[\p{L}\p{N}]+(?:['’-][\p{L}\p{N}]+)*`)
+
+// KeyPhrases returns the Document's n highest-scoring key phrases (or all of
+// them, if n isn't positive), as found by Rapid Automatic Keyword Extraction
+// (RAKE).
+//
+// Candidate phrases are runs of words within a sentence that aren't
+// interrupted by stop words or punctuation. Each word is scored by its degree
+// (the total length of the candidates it appears in) divided by its
+// frequency, and each phrase by the sum of its words' scores. Ties are broken
+// alphabetically.
+func (d *Document) KeyPhrases(n int) []Keyword {
+	phrases := map[string]*Keyword{}
+	freq, degree := map[string]int{}, map[string]int{}
+
+	for i, s := range d.Sentences {
+		var words []string
+		start, end := 0, 0
+
+		flush := func() {
+			if len(words) == 0 {
+				return
+			}
+			phrase := strings.Join(words, " ")
+			if phrases[phrase] == nil {
+				phrases[phrase] = &Keyword{Phrase: phrase}
+			}
+			k := phrases[phrase]
+			k.Mentions = append(k.Mentions, Mention{Sentence: i, Start: start, End: end})
+			for _, w := range words {
+				freq[w]++
+				degree[w] += len(words)
+			}
+			words = nil
+		}
+
+		for _, loc := range reRakeWord.FindAllStringIndex(s.Text, -1) {
+			word := strings.ToLower(s.Text[loc[0]:loc[1]])
+			if len(words) > 0 && strings.TrimSpace(s.Text[end:loc[0]]) != "" {
+				flush()
+			}
+			if _, found := stopWords[word]; found {
+				flush()
+				continue
+			}
+			if len(words) == 0 {
+				start = loc[0]
+			}
+			words = append(words, word)
+			end = loc[1]
+		}
+		flush()
+	}
+
+	keywords := make([]Keyword, 0, len(phrases))
+	for _, k := range phrases {
+		for _, w := range strings.Fields(k.Phrase) {
+			k.Score += float64(degree[w]) / float64(freq[w])
+		}
+		keywords = append(keywords, *k)
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if keywords[i].Score != keywords[j].Score {
+			return keywords[i].Score > keywords[j].Score
+		}
+		return keywords[i].Phrase < keywords[j].Phrase
+	})
+
+	if n > 0 && n < len(keywords) {
+		keywords = keywords[:n]
+	}
+	return keywords
+}
@@ -0,0 +1,32 @@
+package summarize
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeyPhrases(t *testing.T) {
+	d := NewDocument("Compatibility of systems of linear constraints over the set of natural numbers. Criteria of compatibility of a system of linear Diophantine equations, strict inequations, and nonstrict inequations are considered.")
+
+	keywords := d.KeyPhrases(3)
+	expected := []Keyword{
+		{"linear diophantine equations", 8.5, []Mention{{1, 41, 69}}},
+		{"linear constraints", 4.5, []Mention{{0, 28, 46}}},
+		{"natural numbers", 4, []Mention{{0, 63, 78}}},
+	}
+	if !reflect.DeepEqual(keywords, expected) {
+		t.Errorf("KeyPhrases(3): got %+v; expected %+v", keywords, expected)
+	}
+
+	for _, k := range d.KeyPhrases(0) {
+		for _, m := range k.Mentions {
+			if text := d.Sentences[m.Sentence].Text[m.Start:m.End]; strings.ToLower(text) != k.Phrase {
+				t.Errorf("KeyPhrases: mention %q doesn't match %q", text, k.Phrase)
+			}
+		}
+		if k.Phrase == "compatibility" && len(k.Mentions) != 2 {
+			t.Errorf("KeyPhrases: got %d mentions of compatibility; expected 2", len(k.Mentions))
+		}
+	}
+}
@@ -4,6 +4,8 @@ Package summarize implements utilities for computing readability scores, usage s
 package summarize
 
 import (
+	"encoding/json"
+	"io"
 	"sort"
 	"strings"
 	"unicode"
@@ -22,6 +24,8 @@ var wordTokenizer = tokenize.NewWordBoundaryTokenizer()
 type Word struct {
 	Text      string // the actual text
 	Syllables int    // the number of syllables
+	Start     int    // the byte offset of its first character in Content
+	End       int    // the byte offset just past its last character
 }
 
 // A Sentence represents a single sentence in a Document.
@@ -30,6 +34,8 @@ type Sentence struct {
 	Length    int    // the number of words
 	Words     []Word // the words in this sentence
 	Paragraph int
+	Start     int // the byte offset of its first character in Content
+	End       int // the byte offset just past its last character
 }
 
 // A RankedParagraph is a paragraph ranked by its number of keywords.
@@ -94,20 +100,39 @@ func NewDocument(text string) *Document {
 	return &doc
 }
 
+// WriteJSONL writes each of the given Documents to w as a single line of
+// JSON (i.e., JSON Lines), without buffering the others. It stops at, and
+// returns, the first error -- such as a failed write to w.
+func WriteJSONL(w io.Writer, docs ...*Document) error {
+	enc := json.NewEncoder(w)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Initialize calculates the data necessary for computing readability and usage
 // statistics.
 func (d *Document) Initialize() {
 	d.WordFrequency = make(map[string]int)
+	offset := 0
 	for i, paragraph := range strings.Split(d.Content, "\n\n") {
-		for _, s := range sentenceTokenizer.Segment(paragraph) {
+		for _, sent := range sentenceTokenizer.Sentences(paragraph) {
+			s, start := sent.Text, offset+sent.Start
 			wordCount := d.NumWords
 			d.NumSentences++
 			words := []Word{}
+			cursor := 0
 			for _, word := range wordTokenizer.Tokenize(s) {
 				word = strings.TrimSpace(word)
 				if len(word) == 0 {
 					continue
 				}
+				cursor += strings.Index(s[cursor:], word)
+				wordStart := start + cursor
+				cursor += len(word)
 				d.NumCharacters += countChars(word)
 				if _, found := d.WordFrequency[word]; found {
 					d.WordFrequency[word]++
@@ -118,7 +143,9 @@ func (d *Document) Initialize() {
 					d.NumLongWords++
 				}
 				syllables := Syllables(word)
-				words = append(words, Word{Text: word, Syllables: syllables})
+				words = append(words, Word{
+					Text: word, Syllables: syllables,
+					Start: wordStart, End: wordStart + len(word)})
 				d.NumSyllables += float64(syllables)
 				if syllables > 2 {
 					d.NumPolysylWords++
@@ -132,10 +159,141 @@ func (d *Document) Initialize() {
 				Text:      strings.TrimSpace(s),
 				Length:    int(d.NumWords - wordCount),
 				Words:     words,
-				Paragraph: i})
+				Paragraph: i,
+				Start:     start,
+				End:       start + len(s)})
 		}
 		d.NumParagraphs++
+		offset += len(paragraph) + len("\n\n")
+	}
+}
+
+// SentenceAt returns the Document's ith sentence, if it exists.
+//
+// The sentence count is available via the NumSentences field.
+func (d *Document) SentenceAt(i int) (Sentence, bool) {
+	if i < 0 || i >= len(d.Sentences) {
+		return Sentence{}, false
+	}
+	return d.Sentences[i], true
+}
+
+// SentenceAtOffset returns the sentence containing the given byte offset in
+// Content, along with its index. It returns false if the offset isn't within
+// any sentence (e.g., it's in the whitespace between two of them).
+func (d *Document) SentenceAtOffset(offset int) (Sentence, int, bool) {
+	i := sort.Search(len(d.Sentences), func(i int) bool {
+		return d.Sentences[i].End > offset
+	})
+	if i == len(d.Sentences) || offset < d.Sentences[i].Start {
+		return Sentence{}, -1, false
+	}
+	return d.Sentences[i], i, true
+}
+
+// TokensInRange returns the words that overlap the byte range [start, end)
+// of Content, in order. If contained is true, words that only partially
+// overlap it (e.g., at its edges) are excluded. An empty range overlaps no
+// words.
+func (d *Document) TokensInRange(start, end int, contained bool) []Word {
+	words := []Word{}
+	if end <= start {
+		return words
+	}
+
+	i := sort.Search(len(d.Sentences), func(i int) bool {
+		return d.Sentences[i].End > start
+	})
+	for ; i < len(d.Sentences) && d.Sentences[i].Start < end; i++ {
+		sent := d.Sentences[i].Words
+		j := sort.Search(len(sent), func(j int) bool {
+			return sent[j].End > start
+		})
+		for ; j < len(sent) && sent[j].Start < end; j++ {
+			if !contained || (sent[j].Start >= start && sent[j].End <= end) {
+				words = append(words, sent[j])
+			}
+		}
+	}
+
+	return words
+}
+
+// SentenceTexts returns the text of each of the Document's sentences.
+func (d *Document) SentenceTexts() []string {
+	texts := make([]string, len(d.Sentences))
+	for i, s := range d.Sentences {
+		texts[i] = s.Text
+	}
+	return texts
+}
+
+// LastSentenceComplete reports whether the Document's final sentence ends
+// with terminal punctuation (see segment.Terminal), which is useful for
+// deciding whether streamed or truncated text ends mid-sentence. It returns
+// false for a Document without any sentences.
+func (d *Document) LastSentenceComplete() bool {
+	if len(d.Sentences) == 0 {
+		return false
+	}
+	return segment.Terminal(d.Sentences[len(d.Sentences)-1].Text) != 0
+}
+
+// SentenceWindows returns overlapping windows of size consecutive sentences,
+// each starting step sentences after the previous one.
+//
+// The last window may hold fewer than size sentences if the Document's
+// sentences don't divide evenly. Windows share memory with the Sentences
+// field; it returns nil if size or step is less than 1.
+func (d *Document) SentenceWindows(size, step int) [][]Sentence {
+	if size < 1 || step < 1 {
+		return nil
+	}
+	n := len(d.Sentences)
+	windows := [][]Sentence{}
+	for i := 0; i < n; i += step {
+		end := i + size
+		if end > n {
+			end = n
+		}
+		windows = append(windows, d.Sentences[i:end:end])
+		if end == n {
+			break
+		}
+	}
+	return windows
+}
+
+// DuplicateSentences returns groups of the indices of sentences whose text
+// matches after lowercasing and collapsing whitespace, ordered by their first
+// occurrence.
+//
+// If window is positive, a sentence only joins a group if it's within window
+// sentences of the group's last member (e.g., a window of 1 only finds
+// adjacent repeats); otherwise, it starts a new group.
+func (d *Document) DuplicateSentences(window int) [][]int {
+	groups := [][]int{}
+	latest := map[string]int{}
+	for i, s := range d.Sentences {
+		key := strings.ToLower(strings.Join(strings.Fields(s.Text), " "))
+		if g, found := latest[key]; found {
+			members := groups[g]
+			if window <= 0 || i-members[len(members)-1] <= window {
+				groups[g] = append(members, i)
+				continue
+			}
+		}
+		latest[key] = len(groups)
+		groups = append(groups, []int{i})
+	}
+
+	dupes := [][]int{}
+	for _, g := range groups {
+		if len(g) > 1 {
+			dupes = append(dupes, g)
+		}
 	}
+	return dupes
 }
 
 // Assess returns an Assessment for the Document d.
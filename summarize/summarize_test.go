@@ -1,9 +1,13 @@
 package summarize
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/jdkato/twine/internal"
@@ -58,6 +62,220 @@ func TestSummarizePrep(t *testing.T) {
 	}
 }
 
+// failingWriter fails every write after the first n.
+type failingWriter struct {
+	n, writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.writes++; w.writes > w.n {
+		return 0, errors.New("write failed")
+	}
+	return len(p), nil
+}
+
+func TestWriteJSONL(t *testing.T) {
+	docs := []*Document{
+		NewDocument("This is the first document."),
+		NewDocument("This is the second one.\n\nIt has two paragraphs."),
+		NewDocument("And a third."),
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSONL(&buf, docs...); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(docs) {
+		t.Fatalf("WriteJSONL: got %d lines; expected %d", len(lines), len(docs))
+	}
+	for i, line := range lines {
+		var doc Document
+		if err := json.Unmarshal([]byte(line), &doc); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(&doc, docs[i]) {
+			t.Errorf("WriteJSONL: line %d doesn't match its Document", i)
+		}
+	}
+
+	w := &failingWriter{n: 1}
+	if err := WriteJSONL(w, docs...); err == nil || w.writes != 2 {
+		t.Errorf("WriteJSONL: got %v after %d writes; expected an error after 2", err, w.writes)
+	}
+}
+
+func TestSentenceAt(t *testing.T) {
+	d := NewDocument("This is the first sentence. This is the second one.")
+
+	if s, ok := d.SentenceAt(1); !ok || s.Text != "This is the second one." {
+		t.Errorf("SentenceAt(1): got (%q, %v)", s.Text, ok)
+	}
+
+	for _, i := range []int{-1, int(d.NumSentences)} {
+		if _, ok := d.SentenceAt(i); ok {
+			t.Errorf("SentenceAt(%d): expected out of bounds", i)
+		}
+	}
+}
+
+func TestSentenceAtOffset(t *testing.T) {
+	text := "This is the first sentence.  This is the second one.\n\nA new paragraph."
+	d := NewDocument(text)
+
+	second, third := strings.Index(text, "This is the second"), strings.Index(text, "A new")
+	for _, test := range []struct{ offset, index int }{
+		{0, 0}, {second - 3, 0}, {second, 1}, {third - 3, 1}, {third, 2}, {len(text) - 1, 2},
+	} {
+		s, i, ok := d.SentenceAtOffset(test.offset)
+		if !ok || i != test.index || s.Text != d.Sentences[test.index].Text {
+			t.Errorf("SentenceAtOffset(%d): got (%q, %d, %v); expected index %d",
+				test.offset, s.Text, i, ok, test.index)
+		}
+	}
+
+	for _, offset := range []int{-1, second - 2, second - 1, third - 1, len(text)} {
+		if s, i, ok := d.SentenceAtOffset(offset); ok {
+			t.Errorf("SentenceAtOffset(%d): got (%q, %d); expected a gap", offset, s.Text, i)
+		}
+	}
+}
+
+func TestTokensInRange(t *testing.T) {
+	text := "The quick fox jumped.\n\nThe lazy dog slept."
+	d := NewDocument(text)
+
+	texts := func(words []Word) []string {
+		observed := []string{}
+		for _, w := range words {
+			observed = append(observed, w.Text)
+		}
+		return observed
+	}
+
+	quick, slept := strings.Index(text, "quick"), strings.Index(text, "slept")
+	for _, test := range []struct {
+		start, end int
+		contained  bool
+		expected   []string
+	}{
+		{quick, quick + len("quick"), false, []string{"quick"}},
+		{quick + 2, quick + 8, false, []string{"quick", "fox"}},
+		{quick + 2, quick + 8, true, []string{}},
+		{quick + 2, slept + 1, false, []string{"quick", "fox", "jumped", "The", "lazy", "dog", "slept"}},
+		{quick + 2, slept + 1, true, []string{"fox", "jumped", "The", "lazy", "dog"}},
+		{quick + 1, quick + 1, false, []string{}},
+		{0, len(text), true, []string{"The", "quick", "fox", "jumped", "The", "lazy", "dog", "slept"}},
+		{len(text), len(text) + 5, false, []string{}},
+	} {
+		observed := texts(d.TokensInRange(test.start, test.end, test.contained))
+		if !reflect.DeepEqual(observed, test.expected) {
+			t.Errorf("TokensInRange(%d, %d, %v): got %q; expected %q",
+				test.start, test.end, test.contained, observed, test.expected)
+		}
+	}
+}
+
+func TestSentenceTexts(t *testing.T) {
+	d := NewDocument("This is the first sentence. This is the second one.\n\nA new paragraph.")
+	expected := []string{
+		"This is the first sentence.", "This is the second one.", "A new paragraph."}
+
+	if observed := d.SentenceTexts(); !reflect.DeepEqual(observed, expected) {
+		t.Errorf("SentenceTexts: got %q; expected %q", observed, expected)
+	}
+}
+
+func TestOffsets(t *testing.T) {
+	d := NewDocument("It's café time. Bring friends!\n\n  A new paragraph — finally.")
+
+	for _, s := range d.Sentences {
+		if source := d.Content[s.Start:s.End]; source != s.Text {
+			t.Errorf("Sentence at [%d:%d] is %q; expected %q", s.Start, s.End, source, s.Text)
+		}
+		for _, w := range s.Words {
+			if source := d.Content[w.Start:w.End]; source != w.Text {
+				t.Errorf("Word at [%d:%d] is %q; expected %q", w.Start, w.End, source, w.Text)
+			}
+		}
+	}
+
+	if last := d.Sentences[len(d.Sentences)-1]; last.Start != 35 {
+		t.Errorf("Start: got %d; expected 35", last.Start)
+	}
+}
+
+func TestLastSentenceComplete(t *testing.T) {
+	for text, expected := range map[string]bool{
+		"It ended. Then it began again.": true,
+		"Did it end?":                    true,
+		`She said, "It's over!"`:         true,
+		"Wait for it…":                   true,
+		"It ended. Then it began":        false,
+		"First paragraph.\n\nAnd then":   false,
+		"":                               false,
+	} {
+		if observed := NewDocument(text).LastSentenceComplete(); observed != expected {
+			t.Errorf("LastSentenceComplete(%q): got %v; expected %v", text, observed, expected)
+		}
+	}
+}
+
+func TestSentenceWindows(t *testing.T) {
+	d := NewDocument("One. Two. Three. Four. Five.")
+
+	texts := func(windows [][]Sentence) [][]string {
+		observed := [][]string{}
+		for _, w := range windows {
+			sents := []string{}
+			for _, s := range w {
+				sents = append(sents, s.Text)
+			}
+			observed = append(observed, sents)
+		}
+		return observed
+	}
+
+	for _, test := range []struct {
+		size, step int
+		expected   [][]string
+	}{
+		{3, 1, [][]string{
+			{"One.", "Two.", "Three."}, {"Two.", "Three.", "Four."},
+			{"Three.", "Four.", "Five."}}},
+		{2, 2, [][]string{{"One.", "Two."}, {"Three.", "Four."}, {"Five."}}},
+		{1, 3, [][]string{{"One."}, {"Four."}}},
+		{10, 1, [][]string{{"One.", "Two.", "Three.", "Four.", "Five."}}},
+	} {
+		observed := texts(d.SentenceWindows(test.size, test.step))
+		if !reflect.DeepEqual(observed, test.expected) {
+			t.Errorf("SentenceWindows(%d, %d): got %q; expected %q",
+				test.size, test.step, observed, test.expected)
+		}
+	}
+
+	if w := d.SentenceWindows(0, 1); w != nil {
+		t.Errorf("SentenceWindows(0, 1): got %v; expected nil", w)
+	}
+	if w := d.SentenceWindows(2, 0); w != nil {
+		t.Errorf("SentenceWindows(2, 0): got %v; expected nil", w)
+	}
+}
+
+func TestDuplicateSentences(t *testing.T) {
+	d := NewDocument("So we began. So  WE began. Then we stopped. I agree. So we began. I agree.")
+
+	expected := [][]int{{0, 1, 4}, {3, 5}}
+	if observed := d.DuplicateSentences(0); !reflect.DeepEqual(observed, expected) {
+		t.Errorf("DuplicateSentences(0): got %v; expected %v", observed, expected)
+	}
+
+	expected = [][]int{{0, 1}}
+	if observed := d.DuplicateSentences(1); !reflect.DeepEqual(observed, expected) {
+		t.Errorf("DuplicateSentences(1): got %v; expected %v", observed, expected)
+	}
+}
+
 func TestSummarize(t *testing.T) {
 	data := internal.ReadDataFile(filepath.Join(testdata, "article.txt"))
 	d := NewDocument(string(data))
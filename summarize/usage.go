@@ -1,7 +1,9 @@
 package summarize
 
 import (
+	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/montanaflynn/stats"
 )
@@ -37,8 +39,184 @@ func (d *Document) Keywords() map[string]int {
 	return scores
 }
 
+// A WordCount is a word and the number of times it occurs.
+type WordCount struct {
+	Word  string
+	Count int
+}
+
+// A WordScore is a word and a score, such as its density.
+type WordScore struct {
+	Word  string
+	Score float64
+}
+
+// WordFrequencySorted returns the Document's WordFrequency ordered by count
+// (highest first) and then alphabetically.
+func (d *Document) WordFrequencySorted() []WordCount {
+	return sortedCounts(d.WordFrequency)
+}
+
+// KeywordsSorted returns the result of Keywords ordered by count (highest
+// first) and then alphabetically.
+func (d *Document) KeywordsSorted() []WordCount {
+	return sortedCounts(d.Keywords())
+}
+
+// WordDensitySorted returns the result of WordDensity ordered by density
+// (highest first) and then alphabetically.
+func (d *Document) WordDensitySorted() []WordScore {
+	return sortedScores(d.WordDensity())
+}
+
+func sortedCounts(counts map[string]int) []WordCount {
+	sorted := make([]WordCount, 0, len(counts))
+	for word, count := range counts {
+		sorted = append(sorted, WordCount{Word: word, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Word < sorted[j].Word
+	})
+	return sorted
+}
+
+func sortedScores(scores map[string]float64) []WordScore {
+	sorted := make([]WordScore, 0, len(scores))
+	for word, score := range scores {
+		sorted = append(sorted, WordScore{Word: word, Score: score})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Score != sorted[j].Score {
+			return sorted[i].Score > sorted[j].Score
+		}
+		return sorted[i].Word < sorted[j].Word
+	})
+	return sorted
+}
+
 // MeanWordLength returns the mean number of characters per word.
 func (d *Document) MeanWordLength() float64 {
 	val, _ := stats.Round(d.NumCharacters/d.NumWords, 3)
 	return val
 }
+
+// LexStats summarizes the vocabulary richness of a Document.
+type LexStats struct {
+	TTR   float64 // type-token ratio
+	MATTR float64 // moving-average type-token ratio
+	Hapax int     // number of words that occur exactly once
+}
+
+// mattrWindow is the number of words in each of MATTR's windows.
+const mattrWindow = 50
+
+// LexicalDiversity returns the type-token ratio, moving-average type-token
+// ratio (over windows of 50 words), and hapax count of the Document's
+// content words.
+//
+// Since Documents aren't lemmatized, words are compared by their lowercased
+// forms (e.g., "run" and "runs" are distinct types). Stop words are excluded.
+// If there are fewer words than the window size, MATTR equals TTR.
+func (d *Document) LexicalDiversity() LexStats {
+	words := []string{}
+	for _, s := range d.Sentences {
+		for _, w := range s.Words {
+			normalized := strings.ToLower(w.Text)
+			if _, found := stopWords[normalized]; !found {
+				words = append(words, normalized)
+			}
+		}
+	}
+
+	lex := LexStats{}
+	if len(words) == 0 {
+		return lex
+	}
+
+	counts := map[string]int{}
+	for _, w := range words {
+		counts[w]++
+	}
+	for _, n := range counts {
+		if n == 1 {
+			lex.Hapax++
+		}
+	}
+	lex.TTR = float64(len(counts)) / float64(len(words))
+
+	if len(words) < mattrWindow {
+		lex.MATTR = lex.TTR
+		return lex
+	}
+
+	// Slide the window one word at a time, updating its counts incrementally.
+	window := map[string]int{}
+	for _, w := range words[:mattrWindow] {
+		window[w]++
+	}
+	total := float64(len(window))
+	for i := mattrWindow; i < len(words); i++ {
+		if window[words[i-mattrWindow]]--; window[words[i-mattrWindow]] == 0 {
+			delete(window, words[i-mattrWindow])
+		}
+		window[words[i]]++
+		total += float64(len(window))
+	}
+	lex.MATTR = total / float64(len(words)-mattrWindow+1) / mattrWindow
+
+	return lex
+}
+
+// CharStats summarizes the kinds of characters in a Document.
+type CharStats struct {
+	Letters     map[string]int // letters by script (e.g., "Latin" or "Han")
+	Digits      int
+	Punctuation int
+	Whitespace  int
+	Other       int // symbols, marks, and control characters
+}
+
+// CharStats counts the characters in the Document's content by class.
+func (d *Document) CharStats() CharStats {
+	cs := CharStats{Letters: map[string]int{}}
+
+	last, table := "", (*unicode.RangeTable)(nil)
+	for _, r := range d.Content {
+		switch {
+		case unicode.IsLetter(r):
+			if table == nil || !unicode.Is(table, r) {
+				last, table = script(r)
+			}
+			cs.Letters[last]++
+		case unicode.IsDigit(r):
+			cs.Digits++
+		case unicode.IsPunct(r):
+			cs.Punctuation++
+		case unicode.IsSpace(r):
+			cs.Whitespace++
+		default:
+			cs.Other++
+		}
+	}
+
+	return cs
+}
+
+// LettersSorted returns Letters ordered by count (highest first) and then
+// alphabetically by script name.
+func (cs CharStats) LettersSorted() []WordCount {
+	return sortedCounts(cs.Letters)
+}
+
+// script returns the name and range table of the script that r belongs to.
+func script(r rune) (string, *unicode.RangeTable) {
+	for name, table := range unicode.Scripts {
+		if name != "Common" && name != "Inherited" && unicode.Is(table, r) {
+			return name, table
+		}
+	}
+	return "Common", unicode.Common
+}
@@ -2,6 +2,7 @@ package summarize
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -27,3 +28,68 @@ func TestUsage(t *testing.T) {
 		t.Errorf("MeanWordLength: got %f; expected %f", d.MeanWordLength(), 5.163)
 	}
 }
+
+func TestLexicalDiversity(t *testing.T) {
+	d := NewDocument("The cat sat. The cat ran! A dog sat.")
+	observed := d.LexicalDiversity()
+
+	// Content words: cat, sat, cat, ran, dog, sat.
+	expected := LexStats{TTR: 4.0 / 6.0, MATTR: 4.0 / 6.0, Hapax: 2}
+	if observed != expected {
+		t.Errorf("LexicalDiversity: got %+v; expected %+v", observed, expected)
+	}
+
+	text := strings.Repeat("Apples. ", 50) + strings.Repeat("Pears. ", 50)
+	observed = NewDocument(text).LexicalDiversity()
+	if observed.TTR != 0.02 || observed.Hapax != 0 {
+		t.Errorf("LexicalDiversity: got %+v", observed)
+	}
+	// Every 50-word window holds either one or two types.
+	if observed.MATTR <= 0.02 || observed.MATTR >= 0.04 {
+		t.Errorf("LexicalDiversity: unexpected MATTR %f", observed.MATTR)
+	}
+}
+
+func TestCharStats(t *testing.T) {
+	d := NewDocument("Hello, мир! 你好 42 times.\n€")
+	expected := CharStats{
+		Letters:     map[string]int{"Latin": 10, "Cyrillic": 3, "Han": 2},
+		Digits:      2,
+		Punctuation: 3,
+		Whitespace:  5,
+		Other:       1,
+	}
+
+	if observed := d.CharStats(); !reflect.DeepEqual(observed, expected) {
+		t.Errorf("CharStats: got %+v; expected %+v", observed, expected)
+	}
+
+	letters := d.CharStats().LettersSorted()
+	sorted := []WordCount{{"Latin", 10}, {"Cyrillic", 3}, {"Han", 2}}
+	if !reflect.DeepEqual(letters, sorted) {
+		t.Errorf("LettersSorted: got %v; expected %v", letters, sorted)
+	}
+}
+
+func TestSortedUsage(t *testing.T) {
+	d := NewDocument("The cat saw the dog. A dog saw the cat, and the cat ran.")
+
+	counts := d.KeywordsSorted()
+	expected := []WordCount{{"cat", 3}, {"dog", 2}, {"saw", 2}, {"ran", 1}}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("KeywordsSorted: got %v; expected %v", counts, expected)
+	}
+
+	freqs := d.WordFrequencySorted()
+	if len(freqs) != len(d.WordFrequency) || freqs[0] != (WordCount{"cat", 3}) {
+		t.Errorf("WordFrequencySorted: got %v", freqs)
+	}
+
+	density := d.WordDensitySorted()
+	for i := 1; i < len(density); i++ {
+		prev, cur := density[i-1], density[i]
+		if prev.Score < cur.Score || (prev.Score == cur.Score && prev.Word > cur.Word) {
+			t.Errorf("WordDensitySorted: %v before %v", prev, cur)
+		}
+	}
+}